@@ -0,0 +1,286 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// AdminUser is the operator's view of an account, with the fields a user
+// never sees about themselves.
+type AdminUser struct {
+	Id           uuid.UUID  `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Email        string     `json:"email"`
+	Handle       string     `json:"handle,omitempty"`
+	IsRed        bool       `json:"is_chirpy_red"`
+	Verified     bool       `json:"email_verified"`
+	Pending      string     `json:"pending_email,omitempty"`
+	Role         string     `json:"role"`
+	TwoFactor    bool       `json:"two_factor_enabled"`
+	SuspendedAt  *time.Time `json:"suspended_at"`
+	ChirpCount   *int64     `json:"chirp_count,omitempty"`
+	SessionCount *int64     `json:"session_count,omitempty"`
+}
+
+func adminUserFromDB(user database.User) AdminUser {
+	usr := AdminUser{
+		Id:        user.ID,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		Email:     user.Email,
+		Handle:    user.Handle.String,
+		IsRed:     user.IsChirpyRed,
+		Verified:  user.EmailVerifiedAt.Valid,
+		Pending:   user.PendingEmail.String,
+		Role:      user.Role,
+		TwoFactor: user.TotpEnabledAt.Valid,
+	}
+	if user.SuspendedAt.Valid {
+		usr.SuspendedAt = &user.SuspendedAt.Time
+	}
+	return usr
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// listUsers pages through accounts in signup order, optionally only those
+// whose email contains ?q=.
+func (cfg *apiConfig) listUsers(w http.ResponseWriter, r *http.Request) {
+	page, err := queryInt(r, "page", 1)
+	if err != nil || page < 1 {
+		respondWithError(w, "page must be a positive integer", 400)
+		return
+	}
+	perPage, err := queryInt(r, "per_page", defaultPerPage)
+	if err != nil || perPage < 1 || perPage > maxPerPage {
+		respondWithError(w, fmt.Sprintf("per_page must be between 1 and %d", maxPerPage), 400)
+		return
+	}
+
+	search := sql.NullString{}
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		search = sql.NullString{String: likeEscaper.Replace(q), Valid: true}
+	}
+
+	total, err := cfg.db.CountUsers(r.Context(), search)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to count users: %v", err), 500)
+		return
+	}
+	rows, err := cfg.db.ListUsers(
+		r.Context(),
+		database.ListUsersParams{
+			Search: search,
+			Lim:    int32(perPage),
+			Off:    int32((page - 1) * perPage),
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve users: %v", err), 500)
+		return
+	}
+
+	type userPage struct {
+		Users   []AdminUser `json:"users"`
+		Total   int64       `json:"total"`
+		Page    int         `json:"page"`
+		PerPage int         `json:"per_page"`
+	}
+	resp := userPage{Users: []AdminUser{}, Total: total, Page: page, PerPage: perPage}
+	for _, row := range rows {
+		resp.Users = append(resp.Users, adminUserFromDB(row))
+	}
+	respondWithJSON(w, resp, 200)
+}
+
+func (cfg *apiConfig) getUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.userFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := cfg.db.GetUserStats(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve user stats: %v", err), 500)
+		return
+	}
+
+	resp := adminUserFromDB(user)
+	resp.ChirpCount = &stats.ChirpCount
+	resp.SessionCount = &stats.SessionCount
+	respondWithJSON(w, resp, 200)
+}
+
+// suspendUser locks an account out and ends every session it has. Personal
+// access tokens are left alone so unsuspending restores them.
+func (cfg *apiConfig) suspendUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.userFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := cfg.db.SuspendUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to suspend user: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "user is already suspended", 409)
+		return
+	}
+	err = cfg.db.RevokeUserTokens(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to revoke sessions: %v", err), 500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unsuspendUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.userFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := cfg.db.UnsuspendUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to unsuspend user: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "user is not suspended", 409)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// forcePasswordReset throws away the current password, so the only way back
+// in is the reset link mailed to the account.
+func (cfg *apiConfig) forcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.userFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// an empty hash never matches a password
+	err := cfg.db.UpdateUserPassword(
+		r.Context(),
+		database.UpdateUserPasswordParams{
+			ID:             user.ID,
+			HashedPassword: "",
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to clear password: %v", err), 500)
+		return
+	}
+	err = cfg.db.RevokeUserTokens(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to revoke sessions: %v", err), 500)
+		return
+	}
+	err = cfg.sendPasswordReset(r.Context(), cfg.BaseURL, user)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	w.WriteHeader(202)
+}
+
+func (cfg *apiConfig) setChirpyRed(w http.ResponseWriter, r *http.Request) {
+	uid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, "invalid user id", 400)
+		return
+	}
+
+	type req struct {
+		IsRed *bool `json:"is_chirpy_red"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return
+	}
+	if Rdata.IsRed == nil {
+		respondWithError(w, "is_chirpy_red is required", 400)
+		return
+	}
+
+	n, err := cfg.db.SetChirpyRed(
+		r.Context(),
+		database.SetChirpyRedParams{
+			ID:          uid,
+			IsChirpyRed: *Rdata.IsRed,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to update user: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "user not found", 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) deleteUser(w http.ResponseWriter, r *http.Request) {
+	uid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, "invalid user id", 400)
+		return
+	}
+
+	n, err := cfg.db.DeleteUser(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to delete user: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "user not found", 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) userFromPath(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	uid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, "invalid user id", 400)
+		return database.User{}, false
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "user not found", 404)
+		return database.User{}, false
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to find user: %v", err), 500)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
@@ -1,12 +1,13 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"regexp"
-	"strings"
 	"time"
 
 	"github.com/ScooballyD/chirpy/internal/auth"
@@ -14,12 +15,32 @@ import (
 	"github.com/google/uuid"
 )
 
+const refreshTokenTTL = 1440 * time.Hour
+
 type Chirp struct {
-	Id        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserId    uuid.UUID `json:"user_id"`
+	Id           uuid.UUID  `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Body         string     `json:"body"`
+	UserId       uuid.UUID  `json:"user_id"`
+	InReplyTo    *uuid.UUID `json:"in_reply_to"`
+	RechirpOf    *uuid.UUID `json:"rechirp_of"`
+	QuoteOf      *uuid.UUID `json:"quote_of"`
+	Rechirped    *Chirp     `json:"rechirped_chirp,omitempty"`
+	Quoted       *Chirp     `json:"quoted_chirp,omitempty"`
+	Mentions     []Mention  `json:"mentions"`
+	ReplyCount   int64      `json:"reply_count"`
+	RechirpCount int64      `json:"rechirp_count"`
+	LikeCount    int64      `json:"like_count"`
+	LikedByMe    bool       `json:"liked_by_me"`
+	Deleted      bool       `json:"deleted,omitempty"`
+	Hidden       bool       `json:"hidden,omitempty"`
+	Collapsed    bool       `json:"collapsed,omitempty"`
+	MutedWords   []string   `json:"muted_words,omitempty"`
+
+	// muted is set when one of the reader's muted words asks for the chirp
+	// to be left out of listings
+	muted bool
 }
 
 type RespVal struct {
@@ -101,7 +122,13 @@ func (cfg *apiConfig) revokeRefreshToken(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = cfg.db.RevokeToken(r.Context(), Rtkn)
+	Rdata, err := cfg.getRefreshToken(r.Context(), Rtkn)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("error revoking token: %v", err), 401)
+		return
+	}
+
+	err = cfg.db.RevokeToken(r.Context(), Rdata.Token)
 	if err != nil {
 		respondWithError(w, fmt.Sprintf("error revoking token: %v", err), 401)
 		return
@@ -110,39 +137,106 @@ func (cfg *apiConfig) revokeRefreshToken(w http.ResponseWriter, r *http.Request)
 }
 
 func (cfg *apiConfig) saveChirp(chirp Chirp, r *http.Request, w http.ResponseWriter) {
-	chrp, err := cfg.db.CreateChirp(
-		r.Context(),
-		database.CreateChirpParams{
-			Body:   chirp.Body,
-			UserID: chirp.UserId,
-		})
+	var chrp database.Chirp
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		// replies and quotes of a rechirp are really about what it shares
+		parent := uuid.NullUUID{}
+		if chirp.InReplyTo != nil {
+			p, err := chirpFor(r.Context(), q, chirp.UserId, *chirp.InReplyTo)
+			if errors.Is(err, sql.ErrNoRows) {
+				return errParentNotFound
+			}
+			if err != nil {
+				return fmt.Errorf("unable to find chirp being replied to: %v", err)
+			}
+			parent = uuid.NullUUID{UUID: p.ID, Valid: true}
+		}
+		quoted := uuid.NullUUID{}
+		if chirp.QuoteOf != nil {
+			qc, err := chirpFor(r.Context(), q, chirp.UserId, *chirp.QuoteOf)
+			if errors.Is(err, sql.ErrNoRows) {
+				return errQuotedNotFound
+			}
+			if err != nil {
+				return fmt.Errorf("unable to find chirp being quoted: %v", err)
+			}
+			quoted = uuid.NullUUID{UUID: qc.ID, Valid: true}
+		}
+
+		var err error
+		chrp, err = q.CreateChirp(
+			r.Context(),
+			database.CreateChirpParams{
+				Body:      chirp.Body,
+				UserID:    chirp.UserId,
+				InReplyTo: parent,
+				QuoteOf:   quoted,
+			})
+		if err != nil {
+			return err
+		}
+		return linkChirp(r.Context(), q, chrp)
+	})
+	if errors.Is(err, errParentNotFound) || errors.Is(err, errQuotedNotFound) {
+		respondWithError(w, err.Error(), 400)
+		return
+	}
 	if err != nil {
 		fmt.Printf("unable to save chirp: %v", err)
 		w.WriteHeader(500)
 		return
 	}
 
-	resp := Chirp{
-		Id:        chrp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserId:    chirp.UserId,
+	vis, err := cfg.userVisibility(r.Context(), chirp.UserId)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	chirps, err := cfg.chirpsFromDB(r.Context(), vis, []database.Chirp{chrp})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	respondWithJSON(w, chirps[0], 201)
+}
+
+// cleanChirpBody enforces the length limit. It runs on every body that gets
+// stored, whether new or edited. Bodies are stored as written; muted words
+// are applied per reader when chirps are read.
+func cleanChirpBody(body string) (string, error) {
+	if len(body) > 140 {
+		return "", errors.New("Chirp too long")
 	}
+	return body, nil
+}
 
-	respondWithJSON(w, resp, 201)
+// requireVerified reports whether id may post, responding for the caller
+// when they may not.
+func (cfg *apiConfig) requireVerified(w http.ResponseWriter, r *http.Request, id uuid.UUID) bool {
+	if !cfg.RequireVerifiedEmail {
+		return true
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to find user: %v", err), 401)
+		return false
+	}
+	if !user.EmailVerifiedAt.Valid {
+		respondWithError(w, "verify your email address before posting", 403)
+		return false
+	}
+	return true
 }
 
 func (cfg *apiConfig) validateChirpHandler(w http.ResponseWriter, r *http.Request) {
-	tkn, err := auth.GetBearerToken(r.Header)
+	id, err := cfg.authenticate(r, auth.ScopeChirpsWrite)
 	if err != nil {
 		fmt.Println(err)
+		respondWithAuthError(w, err)
 		return
 	}
-	id, err := auth.ValidateJWT(tkn, cfg.Secret)
-	if err != nil {
-		fmt.Println(err)
-		respondWithError(w, "Unauthorized", 401)
+
+	if !cfg.requireVerified(w, r, id) {
 		return
 	}
 
@@ -157,24 +251,11 @@ func (cfg *apiConfig) validateChirpHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if len(chirp.Body) > 140 {
-		respondWithError(w, "Chirp too long", 400)
+	chirp.Body, err = cleanChirpBody(chirp.Body)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
 		return
 	}
-
-	filter := "kerfuffle sharbert fornax"
-	words := strings.Split(chirp.Body, " ")
-	var cleaned []string
-
-	for _, word := range words {
-		match, _ := regexp.MatchString("\\b"+regexp.QuoteMeta(strings.ToLower(word))+"\\b", filter)
-		if match {
-			cleaned = append(cleaned, "****")
-			continue
-		}
-		cleaned = append(cleaned, word)
-	}
-	chirp.Body = strings.Join(cleaned, " ")
 	chirp.UserId = id
 
 	cfg.saveChirp(chirp, r, w)
@@ -183,37 +264,120 @@ func (cfg *apiConfig) validateChirpHandler(w http.ResponseWriter, r *http.Reques
 func (cfg *apiConfig) validateRefreshToken(w http.ResponseWriter, r *http.Request) {
 	Rtkn, err := auth.GetRefreshToken(r.Header)
 	if err != nil {
-		fmt.Println(err)
+		respondWithError(w, fmt.Sprintf("unauthorized: %v", err), 401)
 		return
 	}
 
-	Rdata, err := cfg.db.GetRefreshToken(r.Context(), Rtkn)
+	Rdata, err := cfg.getRefreshToken(r.Context(), Rtkn)
 	if err != nil {
 		respondWithError(w, fmt.Sprintf("unable to retrieve refresh token from database: %v", err), 401)
 		return
 	}
+	if Rdata.RevokedAt.Valid {
+		// a rotated token showing up again means the chain has leaked,
+		// so nothing issued from it can be trusted anymore
+		cfg.revokeTokenFamily(r, Rdata.FamilyID)
+		respondWithError(w, "refresh token has been revoked", 401)
+		return
+	}
 	if Rdata.ExpiresAt.Before(time.Now()) {
 		respondWithError(w, "refresh token has expired", 401)
 		return
 	}
-	if Rdata.RevokedAt.Valid {
+
+	n, err := cfg.db.RevokeActiveToken(r.Context(), Rdata.Token)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to rotate refresh token: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		// lost a race with another request presenting the same token
+		cfg.revokeTokenFamily(r, Rdata.FamilyID)
 		respondWithError(w, "refresh token has been revoked", 401)
 		return
 	}
 
+	newRtkn, err := auth.MakeRefreshToken()
+	if err != nil {
+		fmt.Printf("unable to make refresh token: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	_, err = cfg.db.CreateRefToken(
+		r.Context(),
+		database.CreateRefTokenParams{
+			Token:       auth.HashToken(newRtkn, cfg.TokenKey),
+			UserID:      Rdata.UserID,
+			ExpiresAt:   time.Now().Add(refreshTokenTTL),
+			FamilyID:    Rdata.FamilyID,
+			ParentToken: sql.NullString{String: Rdata.Token, Valid: true},
+		})
+	if err != nil {
+		fmt.Printf("unable to register refresh token: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
 	type tkn struct {
-		Token string `json:"token"`
+		Token    string `json:"token"`
+		RefToken string `json:"refresh_token"`
+	}
+
+	err = cfg.db.TouchSession(
+		r.Context(),
+		database.TouchSessionParams{
+			ID:        Rdata.FamilyID,
+			UserAgent: userAgent(r),
+			Ip:        clientIP(r),
+		})
+	if err != nil {
+		fmt.Printf("unable to update session: %v", err)
 	}
 
-	jwt, err := auth.MakeJWT(Rdata.UserID, cfg.Secret)
+	jwt, err := auth.MakeJWT(Rdata.UserID, Rdata.FamilyID, cfg.Keys)
 	if err != nil {
 		fmt.Printf("unable to make JWT: %v", err)
 		return
 	}
 
 	Tkn := tkn{
-		Token: jwt,
+		Token:    jwt,
+		RefToken: newRtkn,
 	}
 
 	respondWithJSON(w, Tkn, 200)
 }
+
+// getRefreshToken looks a presented refresh token up by its hash. Tokens issued
+// before hashing was introduced are still stored raw; those are found by value
+// and rewritten to their hash the first time they're seen.
+func (cfg *apiConfig) getRefreshToken(ctx context.Context, Rtkn string) (database.RefreshToken, error) {
+	hash := auth.HashToken(Rtkn, cfg.TokenKey)
+	Rdata, err := cfg.db.GetRefreshToken(ctx, hash)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Rdata, err
+	}
+
+	Rdata, err = cfg.db.GetLegacyRefreshToken(ctx, Rtkn)
+	if err != nil {
+		return Rdata, err
+	}
+	err = cfg.db.UpgradeLegacyToken(ctx, database.UpgradeLegacyTokenParams{
+		Hash:        hash,
+		LegacyToken: Rtkn,
+	})
+	if err != nil {
+		return Rdata, fmt.Errorf("unable to upgrade legacy refresh token: %v", err)
+	}
+	Rdata.Token = hash
+	Rdata.Hashed = true
+
+	return Rdata, nil
+}
+
+func (cfg *apiConfig) revokeTokenFamily(r *http.Request, familyID uuid.UUID) {
+	err := cfg.db.RevokeTokenFamily(r.Context(), familyID)
+	if err != nil {
+		fmt.Printf("unable to revoke token family %v: %v", familyID, err)
+	}
+}
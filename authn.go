@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+var (
+	errMissingScope   = errors.New("token is missing the required scope")
+	errSuspended      = errors.New("account is suspended")
+	errLoginRequired  = errors.New("this needs an access token from a login, not a personal access token")
+	errSessionRevoked = errors.New("session has been signed out")
+)
+
+// authenticate resolves the caller from the Authorization header. Access
+// tokens from a login can do anything the user can; personal access tokens
+// are limited to the scopes they were created with.
+func (cfg *apiConfig) authenticate(r *http.Request, scope string) (uuid.UUID, error) {
+	tkn, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !auth.IsPersonalAccessToken(tkn) {
+		return cfg.checkAccessToken(r, tkn)
+	}
+
+	pat, err := cfg.db.GetPersonalAccessToken(r.Context(), auth.HashToken(tkn, cfg.TokenKey))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unknown personal access token")
+	}
+	if pat.RevokedAt.Valid {
+		return uuid.Nil, fmt.Errorf("personal access token has been revoked")
+	}
+	if pat.ExpiresAt.Valid && pat.ExpiresAt.Time.Before(time.Now()) {
+		return uuid.Nil, fmt.Errorf("personal access token has expired")
+	}
+	if !slices.Contains(pat.Scopes, scope) {
+		return uuid.Nil, errMissingScope
+	}
+
+	err = cfg.checkNotSuspended(r, pat.UserID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = cfg.db.TouchPersonalAccessToken(r.Context(), pat.ID)
+	if err != nil {
+		fmt.Printf("unable to record token use: %v", err)
+	}
+	return pat.UserID, nil
+}
+
+// authenticateLogin is authenticate for endpoints that manage the account's
+// credentials, like tokens, sessions and two-factor settings. Only an access
+// token from a login is accepted, so a leaked personal access token can't be
+// used to take the account over.
+func (cfg *apiConfig) authenticateLogin(r *http.Request) (uuid.UUID, error) {
+	tkn, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if auth.IsPersonalAccessToken(tkn) {
+		return uuid.Nil, errLoginRequired
+	}
+	return cfg.checkAccessToken(r, tkn)
+}
+
+// checkAccessToken validates an access token from a login. Signing out a
+// session only revokes its refresh tokens, so the session is looked up too;
+// otherwise the access tokens it already handed out would keep working until
+// they expire.
+func (cfg *apiConfig) checkAccessToken(r *http.Request, tkn string) (uuid.UUID, error) {
+	clms, err := auth.ValidateAccessToken(tkn, cfg.Keys)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if clms.SessionID != uuid.Nil {
+		revoked, err := cfg.db.IsSessionRevoked(r.Context(), clms.SessionID)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("unable to find session: %v", err)
+		}
+		if revoked {
+			return uuid.Nil, errSessionRevoked
+		}
+	}
+	return clms.UserID, cfg.checkNotSuspended(r, clms.UserID)
+}
+
+// checkNotSuspended catches access tokens that were issued before their
+// account was suspended and haven't expired yet.
+func (cfg *apiConfig) checkNotSuspended(r *http.Request, uid uuid.UUID) error {
+	suspended, err := cfg.db.IsUserSuspended(r.Context(), uid)
+	if err != nil {
+		return fmt.Errorf("unable to find user: %v", err)
+	}
+	if suspended {
+		return errSuspended
+	}
+	return nil
+}
+
+// viewer identifies who is reading a public listing, so the response can say
+// things like whether they liked a chirp. A missing or unusable token just
+// means an anonymous reader; it never fails the request.
+func (cfg *apiConfig) viewer(r *http.Request) uuid.NullUUID {
+	if r.Header.Get("Authorization") == "" {
+		return uuid.NullUUID{}
+	}
+	uid, err := cfg.authenticate(r, auth.ScopeProfileRead)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: uid, Valid: true}
+}
+
+func respondWithAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errMissingScope) || errors.Is(err, errSuspended) || errors.Is(err, errLoginRequired) {
+		respondWithError(w, fmt.Sprintf("forbidden: %v", err), 403)
+		return
+	}
+	respondWithError(w, fmt.Sprintf("unauthorized: %v", err), 401)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	mrand "math/rand/v2"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// benchTimeline seeds a synthetic follow graph and times home timeline pages
+// against it, e.g. `chirpy bench-timeline -users 10000 -follows 200`. The
+// seeded accounts are left in place so repeated runs can grow the dataset;
+// POST /admin/reset clears them.
+func benchTimeline(dbQ *database.Queries, args []string) error {
+	fs := flag.NewFlagSet("bench-timeline", flag.ContinueOnError)
+	users := fs.Int("users", 10000, "accounts to seed")
+	follows := fs.Int("follows", 200, "accounts each seeded user follows, before duplicates are dropped")
+	chirps := fs.Int("chirps", 50, "chirps each seeded user posts")
+	days := fs.Int("days", 30, "how far back seeded chirps are spread")
+	skew := fs.Float64("skew", 3, "how strongly follows favour a few popular accounts; 1 is uniform")
+	samples := fs.Int("samples", 200, "timelines to read")
+	pages := fs.Int("pages", 5, "pages to read from each timeline")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if os.Getenv("PLATFORM") != "dev" {
+		return fmt.Errorf("bench-timeline writes test data and only runs with PLATFORM=dev")
+	}
+	if *users < 2 || *follows < 1 || *chirps < 1 || *days < 1 || *skew < 1 || *samples < 1 || *pages < 1 {
+		return fmt.Errorf("-users must be at least 2, -skew at least 1 and every other count positive")
+	}
+
+	ctx := context.Background()
+	tag := make([]byte, 3)
+	rand.Read(tag)
+	prefix := "bench_" + hex.EncodeToString(tag) + "_"
+
+	start := time.Now()
+	ids, err := dbQ.SeedUsers(
+		ctx,
+		database.SeedUsersParams{
+			Prefix: prefix,
+			Count:  int32(*users),
+		})
+	if err != nil {
+		return fmt.Errorf("unable to seed users: %v", err)
+	}
+	nFollows, err := dbQ.SeedFollows(
+		ctx,
+		database.SeedFollowsParams{
+			UserIds: ids,
+			Skew:    *skew,
+			PerUser: int32(*follows),
+		})
+	if err != nil {
+		return fmt.Errorf("unable to seed follows: %v", err)
+	}
+	nChirps, err := dbQ.SeedChirps(
+		ctx,
+		database.SeedChirpsParams{
+			Days:    int32(*days),
+			UserIds: ids,
+			PerUser: int32(*chirps),
+		})
+	if err != nil {
+		return fmt.Errorf("unable to seed chirps: %v", err)
+	}
+	fmt.Printf("seeded %d users, %d follows and %d chirps as %v* in %v\n",
+		len(ids), nFollows, nChirps, prefix, time.Since(start).Round(time.Millisecond))
+
+	var first, later []time.Duration
+	for range *samples {
+		uid := ids[mrand.IntN(len(ids))]
+		var after *cursor
+		for page := range *pages {
+			t := time.Now()
+			rows, err := dbQ.ListHomeTimelineDesc(
+				ctx,
+				database.ListHomeTimelineDescParams{
+					UserID:          uid,
+					HiddenIds:       []uuid.UUID{},
+					CursorCreatedAt: after.createdAt(),
+					CursorID:        after.id(),
+					Lim:             defaultPageLimit,
+				})
+			if err != nil {
+				return fmt.Errorf("unable to read timeline: %v", err)
+			}
+			took := time.Since(t)
+			if page == 0 {
+				first = append(first, took)
+			} else {
+				later = append(later, took)
+			}
+			if len(rows) < defaultPageLimit {
+				break
+			}
+			last := rows[len(rows)-1]
+			after = &cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+	}
+
+	printLatencies("first page", first)
+	printLatencies("later pages", later)
+	return nil
+}
+
+func printLatencies(name string, ds []time.Duration) {
+	if len(ds) == 0 {
+		return
+	}
+	slices.Sort(ds)
+	at := func(p float64) time.Duration {
+		return ds[int(p*float64(len(ds)-1))].Round(time.Microsecond)
+	}
+	fmt.Printf("%-12v n=%-5d p50=%-10v p95=%-10v p99=%-10v max=%v\n",
+		name, len(ds), at(0.50), at(0.95), at(0.99), ds[len(ds)-1].Round(time.Microsecond))
+}
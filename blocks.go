@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// ListedUser is an account on one of the caller's block or mute lists.
+type ListedUser struct {
+	Id        uuid.UUID `json:"id"`
+	Handle    string    `json:"handle,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// blockUser cuts the caller and another account off from each other. Any
+// follows between them are dropped and neither can follow the other again
+// until the block is lifted.
+func (cfg *apiConfig) blockUser(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	user, ok := cfg.userFromPath(w, r)
+	if !ok {
+		return
+	}
+	if user.ID == uid {
+		respondWithError(w, "you can't block yourself", 400)
+		return
+	}
+
+	var n int64
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		n, err = q.BlockUser(
+			r.Context(),
+			database.BlockUserParams{
+				BlockerID: uid,
+				BlockedID: user.ID,
+			})
+		if err != nil {
+			return fmt.Errorf("unable to block user: %v", err)
+		}
+		err = q.DeleteFollowsBetween(
+			r.Context(),
+			database.DeleteFollowsBetweenParams{
+				A: uid,
+				B: user.ID,
+			})
+		if err != nil {
+			return fmt.Errorf("unable to remove follows: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "you have already blocked this user", 409)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unblockUser(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	bid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, "invalid user id", 400)
+		return
+	}
+
+	n, err := cfg.db.UnblockUser(
+		r.Context(),
+		database.UnblockUserParams{
+			BlockerID: uid,
+			BlockedID: bid,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to unblock user: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "you haven't blocked this user", 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// muteUser keeps an account's chirps out of the caller's listings without
+// them knowing; nothing changes for the muted account.
+func (cfg *apiConfig) muteUser(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	user, ok := cfg.userFromPath(w, r)
+	if !ok {
+		return
+	}
+	if user.ID == uid {
+		respondWithError(w, "you can't mute yourself", 400)
+		return
+	}
+
+	n, err := cfg.db.MuteUser(
+		r.Context(),
+		database.MuteUserParams{
+			MuterID: uid,
+			MutedID: user.ID,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to mute user: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "you have already muted this user", 409)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unmuteUser(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	mid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, "invalid user id", 400)
+		return
+	}
+
+	n, err := cfg.db.UnmuteUser(
+		r.Context(),
+		database.UnmuteUserParams{
+			MuterID: uid,
+			MutedID: mid,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to unmute user: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "you haven't muted this user", 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) listBlocks(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileRead)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	rows, err := cfg.db.ListBlocks(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve blocks: %v", err), 500)
+		return
+	}
+
+	resp := []ListedUser{}
+	for _, row := range rows {
+		resp = append(resp, ListedUser{
+			Id:        row.ID,
+			Handle:    row.Handle.String,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+	respondWithJSON(w, resp, 200)
+}
+
+func (cfg *apiConfig) listMutes(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileRead)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	rows, err := cfg.db.ListMutes(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve mutes: %v", err), 500)
+		return
+	}
+
+	resp := []ListedUser{}
+	for _, row := range rows {
+		resp = append(resp, ListedUser{
+			Id:        row.ID,
+			Handle:    row.Handle.String,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+	respondWithJSON(w, resp, 200)
+}
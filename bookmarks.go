@@ -0,0 +1,486 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxFolderNameLength = 50
+
+var errFolderNotFound = errors.New("bookmark folder not found")
+
+type BookmarkFolder struct {
+	Id            uuid.UUID `json:"id"`
+	Name          string    `json:"name"`
+	CreatedAt     time.Time `json:"created_at"`
+	BookmarkCount int64     `json:"bookmark_count"`
+}
+
+// Bookmark is a saved chirp. A chirp deleted after it was saved stays in the
+// list as an unavailable tombstone until the bookmark is removed.
+type Bookmark struct {
+	Chirp        Chirp      `json:"chirp"`
+	FolderId     *uuid.UUID `json:"folder_id"`
+	BookmarkedAt time.Time  `json:"bookmarked_at"`
+	Unavailable  bool       `json:"unavailable"`
+}
+
+type BookmarkPage struct {
+	Bookmarks  []Bookmark `json:"bookmarks"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	PrevCursor string     `json:"prev_cursor,omitempty"`
+}
+
+// listBookmarks pages through the caller's bookmarks, most recently saved
+// first by default. ?folder= narrows it to one folder, or to bookmarks in no
+// folder with ?folder=unfiled.
+func (cfg *apiConfig) listBookmarks(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileRead)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	vis, err := cfg.userVisibility(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	req, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
+		return
+	}
+	if r.URL.Query().Get("sort") == "" {
+		req.desc = true
+	}
+
+	filter := false
+	folder := uuid.NullUUID{}
+	switch v := r.URL.Query().Get("folder"); v {
+	case "":
+	case "unfiled":
+		filter = true
+	default:
+		id, err := uuid.Parse(v)
+		if err != nil {
+			respondWithError(w, "folder must be a folder id or unfiled", 400)
+			return
+		}
+		filter = true
+		folder, err = cfg.bookmarkFolder(r, uid, &id)
+		if errors.Is(err, errFolderNotFound) {
+			respondWithError(w, err.Error(), 404)
+			return
+		}
+		if err != nil {
+			respondWithError(w, fmt.Sprintf("%v", err), 500)
+			return
+		}
+	}
+
+	var rows []database.ListBookmarksDescRow
+	if !req.ascending() {
+		rows, err = cfg.db.ListBookmarksDesc(
+			r.Context(),
+			database.ListBookmarksDescParams{
+				UserID:          uid,
+				FilterFolder:    filter,
+				FolderID:        folder,
+				HiddenIds:       vis.hidden,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+	} else {
+		var asc []database.ListBookmarksAscRow
+		asc, err = cfg.db.ListBookmarksAsc(
+			r.Context(),
+			database.ListBookmarksAscParams{
+				UserID:          uid,
+				FilterFolder:    filter,
+				FolderID:        folder,
+				HiddenIds:       vis.hidden,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+		for _, row := range asc {
+			rows = append(rows, database.ListBookmarksDescRow(row))
+		}
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve bookmarks: %v", err), 500)
+		return
+	}
+
+	p := keysetPage(req, rows, func(row database.ListBookmarksDescRow) cursor {
+		return cursor{CreatedAt: row.BookmarkedAt, ID: row.ID}
+	})
+	chrps := make([]database.Chirp, 0, len(p.items))
+	for _, row := range p.items {
+		chrps = append(chrps, database.Chirp{
+			ID:        row.ID,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Body:      row.Body,
+			UserID:    row.UserID,
+			InReplyTo: row.InReplyTo,
+			DeletedAt: row.DeletedAt,
+			RechirpOf: row.RechirpOf,
+			QuoteOf:   row.QuoteOf,
+			LikeCount: row.LikeCount,
+		})
+	}
+	chirps, err := cfg.chirpsFromDB(r.Context(), vis, chrps)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	resp := BookmarkPage{
+		Bookmarks:  []Bookmark{},
+		NextCursor: cursorString(p.next),
+		PrevCursor: cursorString(p.prev),
+	}
+	for i, row := range p.items {
+		b := Bookmark{
+			Chirp:        chirps[i],
+			BookmarkedAt: row.BookmarkedAt,
+			Unavailable:  chirps[i].Deleted,
+		}
+		if row.FolderID.Valid {
+			b.FolderId = &row.FolderID.UUID
+		}
+		resp.Bookmarks = append(resp.Bookmarks, b)
+	}
+	cfg.setLinkHeader(w, r, p.next, p.prev)
+	respondWithJSON(w, resp, 200)
+}
+
+// addBookmark saves a chirp for the caller, optionally straight into a
+// folder. Bookmarking a rechirp saves the chirp it shares.
+func (cfg *apiConfig) addBookmark(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	type req struct {
+		ChirpId  uuid.UUID  `json:"chirp_id"`
+		FolderId *uuid.UUID `json:"folder_id"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return
+	}
+
+	chirp, err := chirpFor(r.Context(), cfg.db, uid, Rdata.ChirpId)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, errChirpNotFound.Error(), 404)
+		return
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to find chirp: %v", err), 500)
+		return
+	}
+	folder, err := cfg.bookmarkFolder(r, uid, Rdata.FolderId)
+	if errors.Is(err, errFolderNotFound) {
+		respondWithError(w, err.Error(), 404)
+		return
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	n, err := cfg.db.AddBookmark(
+		r.Context(),
+		database.AddBookmarkParams{
+			UserID:   uid,
+			ChirpID:  chirp.ID,
+			FolderID: folder,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to save bookmark: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "you have already bookmarked this chirp", 409)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// moveBookmark puts a bookmark in another folder, or in none when folder_id
+// is null.
+func (cfg *apiConfig) moveBookmark(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	cid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, "invalid chirp id", 400)
+		return
+	}
+
+	type req struct {
+		FolderId *uuid.UUID `json:"folder_id"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return
+	}
+
+	folder, err := cfg.bookmarkFolder(r, uid, Rdata.FolderId)
+	if errors.Is(err, errFolderNotFound) {
+		respondWithError(w, err.Error(), 404)
+		return
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	n, err := cfg.db.MoveBookmark(
+		r.Context(),
+		database.MoveBookmarkParams{
+			UserID:   uid,
+			ChirpID:  cid,
+			FolderID: folder,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to move bookmark: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "bookmark not found", 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) removeBookmark(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	cid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, "invalid chirp id", 400)
+		return
+	}
+
+	n, err := cfg.db.DeleteBookmark(
+		r.Context(),
+		database.DeleteBookmarkParams{
+			UserID:  uid,
+			ChirpID: cid,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to remove bookmark: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "bookmark not found", 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) listBookmarkFolders(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileRead)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	rows, err := cfg.db.ListBookmarkFolders(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve folders: %v", err), 500)
+		return
+	}
+
+	resp := []BookmarkFolder{}
+	for _, row := range rows {
+		resp = append(resp, BookmarkFolder{
+			Id:            row.ID,
+			Name:          row.Name,
+			CreatedAt:     row.CreatedAt,
+			BookmarkCount: row.BookmarkCount,
+		})
+	}
+	respondWithJSON(w, resp, 200)
+}
+
+func (cfg *apiConfig) createBookmarkFolder(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	name, ok := folderName(w, r)
+	if !ok {
+		return
+	}
+
+	folder, err := cfg.db.CreateBookmarkFolder(
+		r.Context(),
+		database.CreateBookmarkFolderParams{
+			UserID: uid,
+			Name:   name,
+		})
+	if isUniqueViolation(err) {
+		respondWithError(w, "you already have a folder with that name", 409)
+		return
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to create folder: %v", err), 500)
+		return
+	}
+
+	respondWithJSON(w, BookmarkFolder{
+		Id:        folder.ID,
+		Name:      folder.Name,
+		CreatedAt: folder.CreatedAt,
+	}, 201)
+}
+
+func (cfg *apiConfig) renameBookmarkFolder(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	fid, err := uuid.Parse(r.PathValue("folderID"))
+	if err != nil {
+		respondWithError(w, "invalid folder id", 400)
+		return
+	}
+	name, ok := folderName(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := cfg.db.RenameBookmarkFolder(
+		r.Context(),
+		database.RenameBookmarkFolderParams{
+			ID:     fid,
+			UserID: uid,
+			Name:   name,
+		})
+	if isUniqueViolation(err) {
+		respondWithError(w, "you already have a folder with that name", 409)
+		return
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to rename folder: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, errFolderNotFound.Error(), 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// deleteBookmarkFolder removes a folder but keeps the bookmarks in it; they
+// become unfiled.
+func (cfg *apiConfig) deleteBookmarkFolder(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	fid, err := uuid.Parse(r.PathValue("folderID"))
+	if err != nil {
+		respondWithError(w, "invalid folder id", 400)
+		return
+	}
+
+	n, err := cfg.db.DeleteBookmarkFolder(
+		r.Context(),
+		database.DeleteBookmarkFolderParams{
+			ID:     fid,
+			UserID: uid,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to delete folder: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, errFolderNotFound.Error(), 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// bookmarkFolder checks that a folder the caller named is theirs. No folder
+// means unfiled.
+func (cfg *apiConfig) bookmarkFolder(r *http.Request, uid uuid.UUID, id *uuid.UUID) (uuid.NullUUID, error) {
+	if id == nil {
+		return uuid.NullUUID{}, nil
+	}
+	folder, err := cfg.db.GetBookmarkFolder(
+		r.Context(),
+		database.GetBookmarkFolderParams{
+			ID:     *id,
+			UserID: uid,
+		})
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.NullUUID{}, errFolderNotFound
+	}
+	if err != nil {
+		return uuid.NullUUID{}, fmt.Errorf("unable to find folder: %v", err)
+	}
+	return uuid.NullUUID{UUID: folder.ID, Valid: true}, nil
+}
+
+func folderName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	type req struct {
+		Name string `json:"name"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return "", false
+	}
+	name := strings.TrimSpace(Rdata.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxFolderNameLength {
+		respondWithError(w, fmt.Sprintf("name must be between 1 and %d characters", maxFolderNameLength), 400)
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,582 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxThreadDepth   = 50
+	maxThreadReplies = 500
+)
+
+type ChirpRevision struct {
+	Id         uuid.UUID `json:"id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+var (
+	errChirpNotFound  = errors.New("chirp not found")
+	errNotChirpAuthor = errors.New("you are not the registered author of this chirp")
+	errEditWindowShut = errors.New("this chirp can no longer be edited")
+	errChirpUnchanged = errors.New("chirp is unchanged")
+	errParentNotFound = errors.New("the chirp being replied to doesn't exist")
+	errQuotedNotFound = errors.New("the chirp being quoted doesn't exist")
+	errRechirpEdit    = errors.New("rechirps can't be edited")
+	errRechirped      = errors.New("you have already rechirped this chirp")
+)
+
+func chirpFromDB(chrp database.Chirp) Chirp {
+	chirp := Chirp{
+		Id:        chrp.ID,
+		CreatedAt: chrp.CreatedAt,
+		UpdatedAt: chrp.UpdatedAt,
+		Body:      chrp.Body,
+		UserId:    chrp.UserID,
+		LikeCount: chrp.LikeCount,
+	}
+	if chrp.InReplyTo.Valid {
+		chirp.InReplyTo = &chrp.InReplyTo.UUID
+	}
+	if chrp.RechirpOf.Valid {
+		chirp.RechirpOf = &chrp.RechirpOf.UUID
+	}
+	if chrp.QuoteOf.Valid {
+		chirp.QuoteOf = &chrp.QuoteOf.UUID
+	}
+	// a tombstone keeps its place in a thread but says nothing about
+	// what was there
+	if chrp.DeletedAt.Valid {
+		chirp.Body = ""
+		chirp.UserId = uuid.Nil
+		chirp.LikeCount = 0
+		chirp.Deleted = true
+	}
+	return chirp
+}
+
+// hydrate fills in what chirps link to, with one query per kind of link for
+// the whole batch rather than one per chirp. Rechirped and quoted chirps are
+// embedded one level deep; a quote inside a quote is left as just its id.
+// Anything personal to the reader, like whether they liked a chirp or which
+// of their muted words it matches, is only filled in when there is a viewer,
+// and originals by authors hidden from them aren't embedded.
+func (cfg *apiConfig) hydrate(ctx context.Context, vis visibility, chirps []Chirp) error {
+	err := cfg.hydrateLinks(ctx, vis.viewer, chirps)
+	if err != nil {
+		return err
+	}
+
+	var ids []uuid.UUID
+	for _, c := range chirps {
+		if c.RechirpOf != nil {
+			ids = append(ids, *c.RechirpOf)
+		}
+		if c.QuoteOf != nil {
+			ids = append(ids, *c.QuoteOf)
+		}
+	}
+	if len(ids) == 0 {
+		vis.filterWords(chirps)
+		return nil
+	}
+	rows, err := cfg.db.GetChirpsByIDs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve shared chirps: %v", err)
+	}
+	originals := make([]Chirp, 0, len(rows))
+	for _, row := range rows {
+		originals = append(originals, chirpFromDB(row))
+	}
+	err = cfg.hydrateLinks(ctx, vis.viewer, originals)
+	if err != nil {
+		return err
+	}
+	byID := map[uuid.UUID]Chirp{}
+	for _, o := range originals {
+		if !vis.hides(o.UserId) {
+			byID[o.Id] = o
+		}
+	}
+
+	for i := range chirps {
+		if chirps[i].RechirpOf != nil {
+			if o, ok := byID[*chirps[i].RechirpOf]; ok {
+				chirps[i].Rechirped = &o
+			}
+		}
+		if chirps[i].QuoteOf != nil {
+			if o, ok := byID[*chirps[i].QuoteOf]; ok {
+				chirps[i].Quoted = &o
+			}
+		}
+	}
+	vis.filterWords(chirps)
+	return nil
+}
+
+func (cfg *apiConfig) hydrateLinks(ctx context.Context, viewer uuid.NullUUID, chirps []Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	for i, c := range chirps {
+		ids[i] = c.Id
+	}
+	rows, err := cfg.db.ListChirpMentions(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve mentions: %v", err)
+	}
+	mentions := map[uuid.UUID][]Mention{}
+	for _, row := range rows {
+		mentions[row.ChirpID] = append(mentions[row.ChirpID], Mention{
+			UserId: row.UserID,
+			Handle: row.Handle.String,
+		})
+	}
+
+	counts, err := cfg.db.CountChirpReplies(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("unable to count replies: %v", err)
+	}
+	replies := map[uuid.UUID]int64{}
+	for _, row := range counts {
+		replies[row.InReplyTo.UUID] = row.ReplyCount
+	}
+	rcounts, err := cfg.db.CountRechirps(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("unable to count rechirps: %v", err)
+	}
+	rechirps := map[uuid.UUID]int64{}
+	for _, row := range rcounts {
+		rechirps[row.RechirpOf.UUID] = row.RechirpCount
+	}
+	liked := map[uuid.UUID]bool{}
+	if viewer.Valid {
+		likes, err := cfg.db.ListLikedChirpIDs(
+			ctx,
+			database.ListLikedChirpIDsParams{
+				UserID:   viewer.UUID,
+				ChirpIds: ids,
+			})
+		if err != nil {
+			return fmt.Errorf("unable to retrieve likes: %v", err)
+		}
+		for _, id := range likes {
+			liked[id] = true
+		}
+	}
+
+	for i := range chirps {
+		chirps[i].Mentions = mentions[chirps[i].Id]
+		if chirps[i].Mentions == nil {
+			chirps[i].Mentions = []Mention{}
+		}
+		chirps[i].ReplyCount = replies[chirps[i].Id]
+		chirps[i].RechirpCount = rechirps[chirps[i].Id]
+		chirps[i].LikedByMe = liked[chirps[i].Id]
+	}
+	return nil
+}
+
+// removeChirp tombstones a chirp rather than deleting the row, so replies to
+// it still hang together. Everything derived from the body goes with it.
+func (cfg *apiConfig) removeChirp(ctx context.Context, id uuid.UUID) error {
+	return cfg.inTx(ctx, func(q *database.Queries) error {
+		n, err := q.TombstoneChirp(ctx, id)
+		if err != nil {
+			return fmt.Errorf("unable to delete chirp: %v", err)
+		}
+		if n == 0 {
+			return errChirpNotFound
+		}
+		err = q.DeleteChirpRevisions(ctx, id)
+		if err != nil {
+			return fmt.Errorf("unable to delete revisions: %v", err)
+		}
+		err = q.DeleteRechirpsOf(ctx, uuid.NullUUID{UUID: id, Valid: true})
+		if err != nil {
+			return fmt.Errorf("unable to delete rechirps: %v", err)
+		}
+		return linkChirp(ctx, q, database.Chirp{ID: id})
+	})
+}
+
+// chirpsFromDB converts and hydrates a page of chirps.
+func (cfg *apiConfig) chirpsFromDB(ctx context.Context, vis visibility, rows []database.Chirp) ([]Chirp, error) {
+	chirps := make([]Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, chirpFromDB(row))
+	}
+	err := cfg.hydrate(ctx, vis, chirps)
+	if err != nil {
+		return nil, err
+	}
+	return chirps, nil
+}
+
+// originalChirp looks up id, following a rechirp through to the chirp it
+// shares. Tombstones count as missing.
+func originalChirp(ctx context.Context, q *database.Queries, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := q.GetChirp(ctx, id)
+	if err == nil && chirp.RechirpOf.Valid {
+		chirp, err = q.GetChirp(ctx, chirp.RechirpOf.UUID)
+	}
+	if err == nil && chirp.DeletedAt.Valid {
+		return chirp, sql.ErrNoRows
+	}
+	return chirp, err
+}
+
+// rechirp shares someone's chirp on the caller's timeline. Each user can
+// rechirp a chirp once.
+func (cfg *apiConfig) rechirp(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeChirpsWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	if !cfg.requireVerified(w, r, uid) {
+		return
+	}
+	cid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, "invalid chirp id", 400)
+		return
+	}
+
+	var chrp database.Chirp
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		orig, err := chirpFor(r.Context(), q, uid, cid)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errChirpNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("unable to find chirp: %v", err)
+		}
+		chrp, err = q.CreateRechirp(
+			r.Context(),
+			database.CreateRechirpParams{
+				UserID:    uid,
+				RechirpOf: uuid.NullUUID{UUID: orig.ID, Valid: true},
+			})
+		if isUniqueViolation(err) {
+			return errRechirped
+		}
+		if err != nil {
+			return fmt.Errorf("unable to rechirp: %v", err)
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errChirpNotFound):
+		respondWithError(w, err.Error(), 404)
+		return
+	case errors.Is(err, errRechirped):
+		respondWithError(w, err.Error(), 409)
+		return
+	case err != nil:
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	vis, err := cfg.userVisibility(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	chirps, err := cfg.chirpsFromDB(r.Context(), vis, []database.Chirp{chrp})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	respondWithJSON(w, chirps[0], 201)
+}
+
+func (cfg *apiConfig) undoRechirp(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeChirpsWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	cid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, "invalid chirp id", 400)
+		return
+	}
+
+	orig, err := originalChirp(r.Context(), cfg.db, cid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to find chirp: %v", err), 404)
+		return
+	}
+	n, err := cfg.db.DeleteRechirp(
+		r.Context(),
+		database.DeleteRechirpParams{
+			UserID:    uid,
+			RechirpOf: uuid.NullUUID{UUID: orig.ID, Valid: true},
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to undo rechirp: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "you haven't rechirped this chirp", 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+type ThreadNode struct {
+	Chirp
+	Replies []*ThreadNode `json:"replies"`
+}
+
+type Thread struct {
+	Ancestors []Chirp     `json:"ancestors"`
+	Chirp     *ThreadNode `json:"chirp"`
+}
+
+// getThread returns the chain of chirps a chirp replies to, oldest first, and
+// the tree of replies under it. Deleted chirps appear as tombstones, and
+// chirps by authors hidden from the reader or hidden by their muted words as
+// empty placeholders, so the shape of the conversation survives.
+func (cfg *apiConfig) getThread(w http.ResponseWriter, r *http.Request) {
+	cid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, "invalid chirp id", 400)
+		return
+	}
+	vis, err := cfg.readerVisibility(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	root, err := cfg.db.GetChirp(r.Context(), cid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to find chirp: %v", err), 404)
+		return
+	}
+	if vis.blocks(root.UserID) {
+		respondWithError(w, errChirpNotFound.Error(), 404)
+		return
+	}
+	ancestors, err := cfg.db.GetChirpAncestors(r.Context(), cid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve thread: %v", err), 500)
+		return
+	}
+	descendants, err := cfg.db.GetChirpDescendants(
+		r.Context(),
+		database.GetChirpDescendantsParams{
+			ID:       cid,
+			MaxDepth: maxThreadDepth,
+			Lim:      maxThreadReplies,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve thread: %v", err), 500)
+		return
+	}
+
+	rows := append(append(ancestors, root), descendants...)
+	chirps, err := cfg.chirpsFromDB(r.Context(), vis, rows)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	for i := range chirps {
+		if chirps[i].Id != cid && (vis.hides(chirps[i].UserId) || chirps[i].muted) {
+			chirps[i].conceal()
+		}
+	}
+
+	// descendants come oldest first, so every parent is in the map before
+	// any of its replies
+	nodes := map[uuid.UUID]*ThreadNode{}
+	for _, c := range chirps[len(ancestors):] {
+		node := &ThreadNode{Chirp: c, Replies: []*ThreadNode{}}
+		nodes[c.Id] = node
+		if c.InReplyTo == nil || c.Id == cid {
+			continue
+		}
+		if parent, ok := nodes[*c.InReplyTo]; ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+
+	resp := Thread{
+		Ancestors: chirps[:len(ancestors)],
+		Chirp:     nodes[cid],
+	}
+	respondWithJSON(w, resp, 200)
+}
+
+// editChirp lets the author replace a chirp's body for a while after posting.
+// The body being replaced is kept as a revision.
+func (cfg *apiConfig) editChirp(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeChirpsWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	if !cfg.requireVerified(w, r, uid) {
+		return
+	}
+
+	cid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, "invalid chirp id", 400)
+		return
+	}
+
+	type req struct {
+		Body string `json:"body"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return
+	}
+	body, err := cleanChirpBody(Rdata.Body)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
+		return
+	}
+
+	var chrp database.Chirp
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		// the row lock keeps two concurrent edits from both recording the
+		// same body as the previous revision
+		old, err := q.GetChirpForUpdate(r.Context(), cid)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && old.DeletedAt.Valid) {
+			return errChirpNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("unable to find chirp: %v", err)
+		}
+		if old.UserID != uid {
+			return errNotChirpAuthor
+		}
+		if old.RechirpOf.Valid {
+			return errRechirpEdit
+		}
+		if time.Since(old.CreatedAt) > cfg.ChirpEditWindow {
+			return errEditWindowShut
+		}
+		if old.Body == body {
+			chrp = old
+			return errChirpUnchanged
+		}
+
+		err = q.CreateChirpRevision(
+			r.Context(),
+			database.CreateChirpRevisionParams{
+				ChirpID:   old.ID,
+				Body:      old.Body,
+				CreatedAt: old.UpdatedAt,
+			})
+		if err != nil {
+			return fmt.Errorf("unable to save revision: %v", err)
+		}
+		chrp, err = q.UpdateChirpBody(
+			r.Context(),
+			database.UpdateChirpBodyParams{
+				ID:   old.ID,
+				Body: body,
+			})
+		if err != nil {
+			return fmt.Errorf("unable to update chirp: %v", err)
+		}
+		return linkChirp(r.Context(), q, chrp)
+	})
+	switch {
+	case errors.Is(err, errChirpUnchanged):
+	case errors.Is(err, errChirpNotFound):
+		respondWithError(w, err.Error(), 404)
+		return
+	case errors.Is(err, errRechirpEdit):
+		respondWithError(w, err.Error(), 400)
+		return
+	case errors.Is(err, errNotChirpAuthor), errors.Is(err, errEditWindowShut):
+		respondWithError(w, err.Error(), 403)
+		return
+	case err != nil:
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	vis, err := cfg.userVisibility(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	chirps, err := cfg.chirpsFromDB(r.Context(), vis, []database.Chirp{chrp})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	respondWithJSON(w, chirps[0], 200)
+}
+
+func (cfg *apiConfig) listChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	cid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, "invalid chirp id", 400)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), cid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to find chirp: %v", err), 404)
+		return
+	}
+	if chirp.DeletedAt.Valid {
+		respondWithError(w, "chirp has been deleted", 404)
+		return
+	}
+	vis, err := cfg.readerVisibility(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	if vis.blocks(chirp.UserID) {
+		respondWithError(w, errChirpNotFound.Error(), 404)
+		return
+	}
+
+	rows, err := cfg.db.ListChirpRevisions(r.Context(), cid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve revisions: %v", err), 500)
+		return
+	}
+
+	resp := []ChirpRevision{}
+	for _, row := range rows {
+		resp = append(resp, ChirpRevision{
+			Id:         row.ID,
+			Body:       row.Body,
+			CreatedAt:  row.CreatedAt,
+			ReplacedAt: row.ReplacedAt,
+		})
+	}
+	respondWithJSON(w, resp, 200)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// runCommand handles the maintenance commands chirpy can be started with
+// instead of serving, e.g. `chirpy create-admin -email admin@example.com`.
+func runCommand(db *sql.DB, dbQ *database.Queries, args []string) error {
+	switch args[0] {
+	case "create-admin":
+		return createAdmin(dbQ, args[1:])
+	case "bench-timeline":
+		return benchTimeline(dbQ, args[1:])
+	case "relink-chirps":
+		return relinkChirps(db, dbQ, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// createAdmin gives an account the admin role, creating it first if needed.
+// The password is read from CHIRPY_ADMIN_PASSWORD or the first line of stdin
+// so it doesn't end up in shell history.
+func createAdmin(dbQ *database.Queries, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "email address of the admin account")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if !validEmail(*email) {
+		return fmt.Errorf("a valid -email is required")
+	}
+
+	ctx := context.Background()
+	user, err := dbQ.GetUser(ctx, *email)
+	if errors.Is(err, sql.ErrNoRows) {
+		pw, err := adminPassword()
+		if err != nil {
+			return err
+		}
+		hasher, err := passwordHasher()
+		if err != nil {
+			return fmt.Errorf("unable to configure password hashing: %v", err)
+		}
+		hash, err := hasher.Hash(pw)
+		if err != nil {
+			return err
+		}
+		user, err = dbQ.CreateUser(
+			ctx,
+			database.CreateUserParams{
+				Email:          *email,
+				HashedPassword: hash,
+			})
+		if err != nil {
+			return fmt.Errorf("unable to create user: %v", err)
+		}
+		// whoever runs this has access to the server, which is as good as
+		// clicking a link in a mailbox
+		_, err = dbQ.VerifyUserEmail(
+			ctx,
+			database.VerifyUserEmailParams{
+				Email: user.Email,
+				ID:    user.ID,
+			})
+		if err != nil {
+			return fmt.Errorf("unable to verify email: %v", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("unable to look up user: %v", err)
+	}
+
+	_, err = dbQ.SetUserRole(
+		ctx,
+		database.SetUserRoleParams{
+			ID:   user.ID,
+			Role: roleAdmin,
+		})
+	if err != nil {
+		return fmt.Errorf("unable to set role: %v", err)
+	}
+	fmt.Printf("%v (%v) is now an admin\n", user.Email, user.ID)
+	return nil
+}
+
+// relinkChirps rebuilds the hashtags and mentions of every chirp from its
+// body, in batches so a large table isn't held in one transaction. Chirps
+// posted before either existed have none until this runs; running it again
+// is harmless.
+func relinkChirps(db *sql.DB, dbQ *database.Queries, args []string) error {
+	fs := flag.NewFlagSet("relink-chirps", flag.ContinueOnError)
+	batch := fs.Int("batch", 500, "chirps relinked per transaction")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *batch < 1 {
+		return fmt.Errorf("-batch must be positive")
+	}
+
+	ctx := context.Background()
+	after := uuid.NullUUID{}
+	total := 0
+	for {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("unable to begin transaction: %v", err)
+		}
+		q := dbQ.WithTx(tx)
+		chirps, err := q.ListChirpsByID(
+			ctx,
+			database.ListChirpsByIDParams{
+				AfterID: after,
+				Lim:     int32(*batch),
+			})
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("unable to retrieve chirps: %v", err)
+		}
+		for _, chirp := range chirps {
+			err = linkChirp(ctx, q, chirp)
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("chirp %v: %v", chirp.ID, err)
+			}
+		}
+		err = tx.Commit()
+		if err != nil {
+			return fmt.Errorf("unable to commit: %v", err)
+		}
+
+		total += len(chirps)
+		if len(chirps) < *batch {
+			break
+		}
+		after = uuid.NullUUID{UUID: chirps[len(chirps)-1].ID, Valid: true}
+	}
+	fmt.Printf("relinked %d chirps\n", total)
+	return nil
+}
+
+func adminPassword() (string, error) {
+	if pw := os.Getenv("CHIRPY_ADMIN_PASSWORD"); pw != "" {
+		return pw, nil
+	}
+
+	fmt.Fprint(os.Stderr, "password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("unable to read password: %v", err)
+	}
+	pw := strings.TrimRight(line, "\r\n")
+	if pw == "" {
+		return "", fmt.Errorf("password can't be empty")
+	}
+	return pw, nil
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"time"
 
@@ -19,6 +22,10 @@ type User struct {
 	Token     string    `json:"token"`
 	RefToken  string    `json:"refresh_token"`
 	IsRed     bool      `json:"is_chirpy_red"`
+	Verified  bool      `json:"email_verified"`
+	Pending   string    `json:"pending_email,omitempty"`
+	Role      string    `json:"role"`
+	Handle    string    `json:"handle,omitempty"`
 }
 
 func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
@@ -34,8 +41,12 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("unable to decoder request: %v", err)
 		return
 	}
+	if !validEmail(usr.Email) {
+		respondWithError(w, "a valid email is required", 400)
+		return
+	}
 
-	hPass, err := auth.HashPassword(usr.Password)
+	hPass, err := cfg.Hasher.Hash(usr.Password)
 	if err != nil {
 		fmt.Println(err)
 	}
@@ -47,28 +58,38 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 			HashedPassword: hPass,
 		})
 	if err != nil {
+		if isUniqueViolation(err) {
+			respondWithError(w, "email is already in use", 409)
+			return
+		}
 		fmt.Printf("unable to create user: %v", err)
+		w.WriteHeader(500)
+		return
 	}
+
+	err = cfg.sendVerification(r.Context(), cfg.BaseURL, user.ID, user.Email)
+	if err != nil {
+		fmt.Println(err)
+	}
+
 	resp := User{
 		Id:        user.ID,
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
 		Email:     user.Email,
 		IsRed:     user.IsChirpyRed,
+		Verified:  user.EmailVerifiedAt.Valid,
+		Role:      user.Role,
+		Handle:    user.Handle.String,
 	}
 	respondWithJSON(w, resp, 201)
 }
 
 func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
-	tkn, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, fmt.Sprintf("unauthorized: %v", err), 401)
-		return
-	}
-	uid, err := auth.ValidateJWT(tkn, cfg.Secret)
+	uid, err := cfg.authenticate(r, auth.ScopeChirpsDelete)
 	if err != nil {
 		fmt.Println(err)
-		respondWithError(w, fmt.Sprintf("unauthorized: %v", err), 401)
+		respondWithAuthError(w, err)
 		return
 	}
 
@@ -88,97 +109,133 @@ func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, fmt.Sprintf("unable to find chirp: %v", err), 404)
 		return
 	}
+	if chirp.DeletedAt.Valid {
+		respondWithError(w, "chirp has already been deleted", 404)
+		return
+	}
 	if uid != chirp.UserID {
 		respondWithError(w, fmt.Sprintf("you are not the registered author of chirp %v", cid), 403)
 		return
 	}
 
-	err = cfg.db.DeleteChirp(r.Context(), chirp.ID)
+	err = cfg.removeChirp(r.Context(), chirp.ID)
+	if errors.Is(err, errChirpNotFound) {
+		respondWithError(w, "chirp has already been deleted", 404)
+		return
+	}
 	if err != nil {
 		fmt.Printf("unable to delete chirp: %v", err)
+		w.WriteHeader(500)
 		return
 	}
 	respondWithJSON(w, nil, 204)
 }
 
+type ChirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+}
+
+// getChirps lists chirps as a bare array, with the neighbouring pages only in
+// the Link header so the body stays what clients have always parsed. Paging
+// is opt-in: without ?limit= or ?cursor= every chirp is returned, as it was
+// before pages existed.
 func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
-	chirps, err := cfg.db.GetChirps(r.Context())
+	req, err := parsePageRequest(r)
 	if err != nil {
-		fmt.Printf("unable to retrieve chirps: %v", err)
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
 		return
 	}
-	if r.URL.Query().Get("sort") == "desc" {
-		chirps, err = cfg.db.GetChirpsDesc(r.Context())
-		if err != nil {
-			fmt.Printf("unable to retrieve chirps: %v", err)
-			return
-		}
+	if q := r.URL.Query(); !q.Has("limit") && !q.Has("cursor") {
+		req.limit = math.MaxInt32 - 1
 	}
-
-	idStr := r.PathValue("chirpID")
-	if idStr != "" {
-		id, err := uuid.Parse(idStr)
-		if err != nil {
-			fmt.Printf("unable to parse id: %v", err)
-			return
-		}
-		resp, err := cfg.db.GetChirp(r.Context(), id)
-		if err != nil {
-			er := fmt.Sprintf("unable to retrieve chirp: %v", err)
-			respondWithError(w, er, 404)
-			return
-		}
-		if resp.ID == uuid.Nil {
-			respondWithError(w, "chirp does not exist", 404)
-			return
-		}
-		chirp := Chirp{
-			Id:        resp.ID,
-			CreatedAt: resp.CreatedAt,
-			UpdatedAt: resp.UpdatedAt,
-			Body:      resp.Body,
-			UserId:    resp.UserID,
-		}
-		respondWithJSON(w, chirp, 200)
+	vis, err := cfg.readerVisibility(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
 		return
 	}
-	var resp []Chirp
 
-	Aid := r.URL.Query().Get("author_id")
-	if Aid != "" {
+	author := uuid.NullUUID{}
+	if Aid := r.URL.Query().Get("author_id"); Aid != "" {
 		id, err := uuid.Parse(Aid)
 		if err != nil {
-			fmt.Printf("unable to parse id: %v", err)
+			respondWithError(w, "invalid author_id", 400)
 			return
 		}
+		author = uuid.NullUUID{UUID: id, Valid: true}
+	}
 
-		for _, chirp := range chirps {
-			chrp := Chirp{
-				Id:        chirp.ID,
-				CreatedAt: chirp.CreatedAt,
-				UpdatedAt: chirp.UpdatedAt,
-				Body:      chirp.Body,
-				UserId:    chirp.UserID,
-			}
-			if chrp.UserId == id {
-				resp = append(resp, chrp)
-			}
-		}
-		respondWithJSON(w, resp, 200)
+	var chirps []database.Chirp
+	if req.ascending() {
+		chirps, err = cfg.db.ListChirpsAsc(
+			r.Context(),
+			database.ListChirpsAscParams{
+				AuthorID:        author,
+				HiddenIds:       vis.hidden,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+	} else {
+		chirps, err = cfg.db.ListChirpsDesc(
+			r.Context(),
+			database.ListChirpsDescParams{
+				AuthorID:        author,
+				HiddenIds:       vis.hidden,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve chirps: %v", err), 500)
 		return
 	}
 
-	for _, chirp := range chirps {
-		chrp := Chirp{
-			Id:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserId:    chirp.UserID,
-		}
-		resp = append(resp, chrp)
+	p := keysetPage(req, chirps, func(c database.Chirp) cursor {
+		return cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+	})
+	resp, err := cfg.chirpsFromDB(r.Context(), vis, p.items)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
 	}
-	respondWithJSON(w, resp, 200)
+	cfg.setLinkHeader(w, r, p.next, p.prev)
+	respondWithJSON(w, listed(resp), 200)
+}
+
+func (cfg *apiConfig) getChirp(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, "invalid chirp id", 400)
+		return
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), id)
+	if err != nil {
+		er := fmt.Sprintf("unable to retrieve chirp: %v", err)
+		respondWithError(w, er, 404)
+		return
+	}
+	if chirp.DeletedAt.Valid {
+		respondWithError(w, "chirp has been deleted", 404)
+		return
+	}
+	vis, err := cfg.readerVisibility(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	if vis.blocks(chirp.UserID) {
+		respondWithError(w, errChirpNotFound.Error(), 404)
+		return
+	}
+	chirps, err := cfg.chirpsFromDB(r.Context(), vis, []database.Chirp{chirp})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	respondWithJSON(w, chirps[0], 200)
 }
 
 func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
@@ -195,19 +252,82 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := cfg.db.GetUser(r.Context(), usr.Email)
+	wait, err := cfg.startLoginAttempt(r.Context(), r, usr.Email)
 	if err != nil {
-		fmt.Printf("error retrieving user info: %v", err)
+		fmt.Println(err)
+		w.WriteHeader(500)
 		return
 	}
-	err = auth.CheckPasswordHash(usr.Password, user.HashedPassword)
+	if wait > 0 {
+		respondWithThrottle(w, wait)
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), usr.Email)
 	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			fmt.Printf("error retrieving user info: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		// check against a throwaway hash so unknown emails take as long
+		// to reject as wrong passwords
+		user.HashedPassword = cfg.dummyHash
+	}
+	err = auth.CheckPasswordHash(usr.Password, user.HashedPassword)
+	if err != nil || user.ID == uuid.Nil {
 		fmt.Println(err)
 		respondWithError(w, "Incorrect email or password", 401)
 		return
 	}
+	if user.SuspendedAt.Valid {
+		respondWithError(w, "account is suspended", 403)
+		return
+	}
+	if cfg.Hasher.NeedsRehash(user.HashedPassword) {
+		cfg.rehashPassword(r, user.ID, usr.Password)
+	}
+
+	if user.TotpEnabledAt.Valid {
+		// the password was right, but the run of failures stands until the
+		// code is accepted, or logging in again would reset the count on
+		// code guesses
+		cfg.forgiveLoginAttempt(r.Context(), r, usr.Email)
+		mfaTkn, err := auth.MakeMFAToken(user.ID, cfg.Keys)
+		if err != nil {
+			fmt.Printf("unable to make mfa token: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		type mfa struct {
+			Required bool   `json:"mfa_required"`
+			Token    string `json:"mfa_token"`
+		}
+		respondWithJSON(w, mfa{Required: true, Token: mfaTkn}, 200)
+		return
+	}
+
+	cfg.clearLoginFailures(r.Context(), r, usr.Email)
+	cfg.issueSession(w, r, user)
+}
 
-	tkn, err := auth.MakeJWT(user.ID, cfg.Secret)
+// issueSession finishes a login by handing out an access token and the first
+// refresh token of a new chain.
+func (cfg *apiConfig) issueSession(w http.ResponseWriter, r *http.Request, user database.User) {
+	session, err := cfg.db.CreateSession(
+		r.Context(),
+		database.CreateSessionParams{
+			UserID:    user.ID,
+			UserAgent: userAgent(r),
+			Ip:        clientIP(r),
+		})
+	if err != nil {
+		fmt.Printf("unable to create session: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	tkn, err := auth.MakeJWT(user.ID, session.ID, cfg.Keys)
 	if err != nil {
 		fmt.Printf("unable to make JWT: %v", err)
 		return
@@ -221,9 +341,10 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 	_, err = cfg.db.CreateRefToken(
 		r.Context(),
 		database.CreateRefTokenParams{
-			Token:     Rtkn,
+			Token:     auth.HashToken(Rtkn, cfg.TokenKey),
 			UserID:    user.ID,
-			ExpiresAt: time.Now().Add(1440 * time.Hour),
+			ExpiresAt: time.Now().Add(refreshTokenTTL),
+			FamilyID:  session.ID,
 		})
 	if err != nil {
 		fmt.Printf("unable to register refresh token: %v", err)
@@ -237,27 +358,27 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		Token:     tkn,
 		RefToken:  Rtkn,
 		IsRed:     user.IsChirpyRed,
+		Verified:  user.EmailVerifiedAt.Valid,
+		Pending:   user.PendingEmail.String,
+		Role:      user.Role,
+		Handle:    user.Handle.String,
 	}
 
 	respondWithJSON(w, Rusr, 200)
 }
 
 func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
-	tkn, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, fmt.Sprintf("unauthorized: %v", err), 401)
-		return
-	}
-	id, err := auth.ValidateJWT(tkn, cfg.Secret)
+	id, err := cfg.authenticate(r, auth.ScopeProfileWrite)
 	if err != nil {
 		fmt.Println(err)
-		respondWithError(w, fmt.Sprintf("unauthorized: %v", err), 401)
+		respondWithAuthError(w, err)
 		return
 	}
 
 	type Usr struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
+		Handle   string `json:"handle"`
 	}
 	usr := Usr{}
 
@@ -267,21 +388,112 @@ func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("unable to decoder request: %v", err)
 		return
 	}
+	if usr.Handle != "" && !validHandle(usr.Handle) {
+		respondWithError(w, "handle must be 3 to 30 letters, digits or underscores", 400)
+		return
+	}
+	// a personal access token with profile:write may change the handle, but
+	// only a login may change what the account is logged in with
+	if usr.Password != "" || usr.Email != "" {
+		id, err = cfg.authenticateLogin(r)
+		if err != nil {
+			fmt.Println(err)
+			respondWithAuthError(w, err)
+			return
+		}
+	}
 
-	hPass, err := auth.HashPassword(usr.Password)
+	user, err := cfg.db.GetUserByID(r.Context(), id)
 	if err != nil {
-		fmt.Println(err)
+		respondWithError(w, fmt.Sprintf("unable to find user: %v", err), 404)
+		return
 	}
 
-	usrData, err := cfg.db.UpdateUserCredentials(
-		r.Context(),
-		database.UpdateUserCredentialsParams{
-			ID:             id,
-			Email:          usr.Email,
-			HashedPassword: hPass,
-		})
+	// done first so a taken handle doesn't leave the rest half applied
+	if usr.Handle != "" && usr.Handle != user.Handle.String {
+		err = cfg.db.SetUserHandle(
+			r.Context(),
+			database.SetUserHandleParams{
+				ID:     id,
+				Handle: sql.NullString{String: usr.Handle, Valid: true},
+			})
+		if isUniqueViolation(err) {
+			respondWithError(w, "handle is already taken", 409)
+			return
+		}
+		if err != nil {
+			respondWithError(w, fmt.Sprintf("unable to update handle: %v", err), 500)
+			return
+		}
+	}
+
+	if usr.Password != "" {
+		hPass, err := cfg.Hasher.Hash(usr.Password)
+		if err != nil {
+			respondWithError(w, fmt.Sprintf("%v", err), 500)
+			return
+		}
+		err = cfg.db.UpdateUserPassword(
+			r.Context(),
+			database.UpdateUserPasswordParams{
+				ID:             id,
+				HashedPassword: hPass,
+			})
+		if err != nil {
+			respondWithError(w, fmt.Sprintf("unable to update credentials: %v", err), 401)
+			return
+		}
+
+		// every other session was opened with the old password
+		err = cfg.db.RevokeUserTokensExcept(
+			r.Context(),
+			database.RevokeUserTokensExceptParams{
+				UserID:   id,
+				FamilyID: cfg.currentSession(r),
+			})
+		if err != nil {
+			fmt.Printf("unable to revoke other sessions: %v", err)
+		}
+	}
+
+	// a new address only replaces the old one once it has been confirmed
+	if usr.Email != "" && usr.Email != user.Email {
+		if !validEmail(usr.Email) {
+			respondWithError(w, "a valid email is required", 400)
+			return
+		}
+		_, err = cfg.db.GetUser(r.Context(), usr.Email)
+		if err == nil {
+			respondWithError(w, "email is already in use", 409)
+			return
+		}
+		err = cfg.db.SetPendingEmail(
+			r.Context(),
+			database.SetPendingEmailParams{
+				ID:           id,
+				PendingEmail: sql.NullString{String: usr.Email, Valid: true},
+			})
+		if err != nil {
+			respondWithError(w, fmt.Sprintf("unable to update credentials: %v", err), 500)
+			return
+		}
+		err = cfg.sendVerification(r.Context(), cfg.BaseURL, id, usr.Email)
+		if err != nil {
+			fmt.Println(err)
+		}
+	} else if usr.Email == user.Email && user.PendingEmail.Valid {
+		err = cfg.db.SetPendingEmail(
+			r.Context(),
+			database.SetPendingEmailParams{ID: id})
+		if err != nil {
+			respondWithError(w, fmt.Sprintf("unable to update credentials: %v", err), 500)
+			return
+		}
+	}
+
+	usrData, err := cfg.db.GetUserByID(r.Context(), id)
 	if err != nil {
-		respondWithError(w, fmt.Sprintf("unable to update credentials: %v", err), 401)
+		respondWithError(w, fmt.Sprintf("unable to find user: %v", err), 404)
 		return
 	}
 
@@ -291,7 +503,31 @@ func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
 		CreatedAt: usrData.CreatedAt,
 		Email:     usrData.Email,
 		IsRed:     usrData.IsChirpyRed,
+		Verified:  usrData.EmailVerifiedAt.Valid,
+		Pending:   usrData.PendingEmail.String,
+		Role:      usrData.Role,
+		Handle:    usrData.Handle.String,
 	}
 
 	respondWithJSON(w, pl, 200)
 }
+
+// rehashPassword brings a stored hash up to the current policy. It runs after
+// a successful login, the only time the plaintext is available; failing here
+// only means trying again next time.
+func (cfg *apiConfig) rehashPassword(r *http.Request, id uuid.UUID, password string) {
+	hPass, err := cfg.Hasher.Hash(password)
+	if err != nil {
+		fmt.Printf("unable to rehash password: %v", err)
+		return
+	}
+	err = cfg.db.UpdateUserPassword(
+		r.Context(),
+		database.UpdateUserPasswordParams{
+			ID:             id,
+			HashedPassword: hPass,
+		})
+	if err != nil {
+		fmt.Printf("unable to store rehashed password: %v", err)
+	}
+}
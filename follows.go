@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// FollowUser is an account in someone's followers or following list.
+type FollowUser struct {
+	Id         uuid.UUID `json:"id"`
+	Handle     string    `json:"handle,omitempty"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type FollowPage struct {
+	Users      []FollowUser `json:"users"`
+	Total      int64        `json:"total"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	PrevCursor string       `json:"prev_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) followUser(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	user, ok := cfg.userFromPath(w, r)
+	if !ok {
+		return
+	}
+	if user.ID == uid {
+		respondWithError(w, "you can't follow yourself", 400)
+		return
+	}
+	blocked, err := cfg.db.IsBlockedBetween(
+		r.Context(),
+		database.IsBlockedBetweenParams{
+			A: uid,
+			B: user.ID,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to check blocks: %v", err), 500)
+		return
+	}
+	if blocked {
+		respondWithError(w, "you can't follow this user", 403)
+		return
+	}
+
+	n, err := cfg.db.FollowUser(
+		r.Context(),
+		database.FollowUserParams{
+			FollowerID: uid,
+			FolloweeID: user.ID,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to follow user: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "you already follow this user", 409)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	fid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, "invalid user id", 400)
+		return
+	}
+
+	n, err := cfg.db.UnfollowUser(
+		r.Context(),
+		database.UnfollowUserParams{
+			FollowerID: uid,
+			FolloweeID: fid,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to unfollow user: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "you don't follow this user", 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) listFollowers(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, true)
+}
+
+func (cfg *apiConfig) listFollowing(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, false)
+}
+
+// listFollows pages through who follows a user, or who they follow, most
+// recent follow first by default. Both lists are public.
+func (cfg *apiConfig) listFollows(w http.ResponseWriter, r *http.Request, followers bool) {
+	uid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, "invalid user id", 400)
+		return
+	}
+	req, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
+		return
+	}
+	if r.URL.Query().Get("sort") == "" {
+		req.desc = true
+	}
+	vis, err := cfg.readerVisibility(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	if vis.blocks(uid) {
+		respondWithError(w, "user not found", 404)
+		return
+	}
+
+	counts, err := cfg.db.GetFollowCounts(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to count follows: %v", err), 500)
+		return
+	}
+	rows, err := cfg.followRows(r, uid, req, followers)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve follows: %v", err), 500)
+		return
+	}
+
+	p := keysetPage(req, rows, func(row database.ListFollowersDescRow) cursor {
+		return cursor{CreatedAt: row.FollowedAt, ID: row.ID}
+	})
+	resp := FollowPage{
+		Users:      []FollowUser{},
+		Total:      counts.FollowingCount,
+		NextCursor: cursorString(p.next),
+		PrevCursor: cursorString(p.prev),
+	}
+	if followers {
+		resp.Total = counts.FollowerCount
+	}
+	for _, row := range p.items {
+		resp.Users = append(resp.Users, FollowUser{
+			Id:         row.ID,
+			Handle:     row.Handle.String,
+			FollowedAt: row.FollowedAt,
+		})
+	}
+	cfg.setLinkHeader(w, r, p.next, p.prev)
+	respondWithJSON(w, resp, 200)
+}
+
+func (cfg *apiConfig) followRows(r *http.Request, uid uuid.UUID, req pageRequest, followers bool) ([]database.ListFollowersDescRow, error) {
+	var rows []database.ListFollowersDescRow
+	switch {
+	case followers && !req.ascending():
+		return cfg.db.ListFollowersDesc(
+			r.Context(),
+			database.ListFollowersDescParams{
+				UserID:          uid,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+	case followers:
+		asc, err := cfg.db.ListFollowersAsc(
+			r.Context(),
+			database.ListFollowersAscParams{
+				UserID:          uid,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+		for _, row := range asc {
+			rows = append(rows, database.ListFollowersDescRow(row))
+		}
+		return rows, err
+	case !req.ascending():
+		desc, err := cfg.db.ListFollowingDesc(
+			r.Context(),
+			database.ListFollowingDescParams{
+				UserID:          uid,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+		for _, row := range desc {
+			rows = append(rows, database.ListFollowersDescRow(row))
+		}
+		return rows, err
+	default:
+		asc, err := cfg.db.ListFollowingAsc(
+			r.Context(),
+			database.ListFollowingAscParams{
+				UserID:          uid,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+		for _, row := range asc {
+			rows = append(rows, database.ListFollowersDescRow(row))
+		}
+		return rows, err
+	}
+}
+
+// homeTimeline lists chirps from the caller and everyone they follow, newest
+// first by default. It's put together on read rather than fanned out on
+// write: each followed account's latest chirps come straight off the
+// (user_id, created_at, id) index, so a page costs one short index scan per
+// account followed no matter how big the chirps table gets, and following or
+// unfollowing someone shows up immediately.
+func (cfg *apiConfig) homeTimeline(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileRead)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	req, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
+		return
+	}
+	if r.URL.Query().Get("sort") == "" {
+		req.desc = true
+	}
+	vis, err := cfg.userVisibility(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	rows, err := cfg.homeTimelineRows(r, vis, req)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve timeline: %v", err), 500)
+		return
+	}
+	cfg.respondWithChirpPage(w, r, vis, req, rows)
+}
+
+func (cfg *apiConfig) homeTimelineRows(r *http.Request, vis visibility, req pageRequest) ([]database.Chirp, error) {
+	if req.ascending() {
+		return cfg.db.ListHomeTimelineAsc(
+			r.Context(),
+			database.ListHomeTimelineAscParams{
+				UserID:          vis.viewer.UUID,
+				HiddenIds:       vis.hidden,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+	}
+	return cfg.db.ListHomeTimelineDesc(
+		r.Context(),
+		database.ListHomeTimelineDescParams{
+			UserID:          vis.viewer.UUID,
+			HiddenIds:       vis.hidden,
+			CursorCreatedAt: req.after.createdAt(),
+			CursorID:        req.after.id(),
+			Lim:             req.fetchLimit(),
+		})
+}
@@ -11,3 +11,5 @@ require (
 require golang.org/x/crypto v0.29.0
 
 require github.com/golang-jwt/jwt/v4 v4.5.1
+
+require golang.org/x/sys v0.27.0 // indirect
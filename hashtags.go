@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxHashtagLength = 100
+
+// a # only starts a tag at the start of the body or after something that
+// can't be part of a word, so URL fragments and "a#b" don't count
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/#])#([\p{L}\p{N}_]+)`)
+
+// extractHashtags returns the normalized tags in body, without duplicates,
+// in the order they first appear. All-digit tags like #1 are left out.
+func extractHashtags(body string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, m := range hashtagPattern.FindAllStringSubmatch(body, -1) {
+		tag := normalizeHashtag(m[1])
+		if tag == "" || seen[tag] || strings.Trim(tag, "0123456789") == "" {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// normalizeHashtag lowercases tag and drops a leading #. It returns "" for
+// anything that can't be a tag.
+func normalizeHashtag(tag string) string {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	if tag == "" || len(tag) > maxHashtagLength {
+		return ""
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return ""
+		}
+	}
+	return tag
+}
+
+// setChirpHashtags makes the chirp's tags match its body. Run it in the same
+// transaction that wrote the body.
+func setChirpHashtags(ctx context.Context, q *database.Queries, chirpID uuid.UUID, body string) error {
+	err := q.DeleteChirpHashtags(ctx, chirpID)
+	if err != nil {
+		return fmt.Errorf("unable to clear hashtags: %v", err)
+	}
+
+	tags := extractHashtags(body)
+	if len(tags) == 0 {
+		return nil
+	}
+	err = q.AddChirpHashtags(
+		ctx,
+		database.AddChirpHashtagsParams{
+			Names:   tags,
+			ChirpID: chirpID,
+		})
+	if err != nil {
+		return fmt.Errorf("unable to save hashtags: %v", err)
+	}
+	return nil
+}
+
+func (cfg *apiConfig) getHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := normalizeHashtag(r.PathValue("tag"))
+	if tag == "" {
+		respondWithError(w, "invalid hashtag", 400)
+		return
+	}
+	req, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
+		return
+	}
+	vis, err := cfg.readerVisibility(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	var chirps []database.Chirp
+	if req.ascending() {
+		chirps, err = cfg.db.ListHashtagChirpsAsc(
+			r.Context(),
+			database.ListHashtagChirpsAscParams{
+				Name:            tag,
+				HiddenIds:       vis.hidden,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+	} else {
+		chirps, err = cfg.db.ListHashtagChirpsDesc(
+			r.Context(),
+			database.ListHashtagChirpsDescParams{
+				Name:            tag,
+				HiddenIds:       vis.hidden,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve chirps: %v", err), 500)
+		return
+	}
+
+	cfg.respondWithChirpPage(w, r, vis, req, chirps)
+}
+
+// autocompleteHashtags suggests the most used tags starting with ?q=.
+func (cfg *apiConfig) autocompleteHashtags(w http.ResponseWriter, r *http.Request) {
+	prefix := normalizeHashtag(r.URL.Query().Get("q"))
+	if prefix == "" {
+		respondWithError(w, "q must be the start of a hashtag", 400)
+		return
+	}
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 50 {
+			respondWithError(w, "limit must be between 1 and 50", 400)
+			return
+		}
+		limit = n
+	}
+
+	// tags are only letters, digits and _, so the prefix needs no LIKE
+	// escaping beyond the _ wildcard
+	rows, err := cfg.db.AutocompleteHashtags(
+		r.Context(),
+		database.AutocompleteHashtagsParams{
+			Prefix: strings.ReplaceAll(prefix, "_", `\_`),
+			Lim:    int32(limit),
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve hashtags: %v", err), 500)
+		return
+	}
+
+	type hashtag struct {
+		Name       string `json:"name"`
+		ChirpCount int64  `json:"chirp_count"`
+	}
+	resp := []hashtag{}
+	for _, row := range rows {
+		resp = append(resp, hashtag{Name: row.Name, ChirpCount: row.ChirpCount})
+	}
+	respondWithJSON(w, resp, 200)
+}
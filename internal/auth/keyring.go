@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// LegacyKeyID is the kid given to the HS256 key built from the legacy
+	// SECRET. Tokens signed before key IDs existed carry no kid and are
+	// checked against it.
+	LegacyKeyID = "legacy-hs256"
+	// DefaultKeyID is the kid given to PrivateKey when no SigningKID is set.
+	DefaultKeyID = "default"
+)
+
+// Key is a single JWT key. Asymmetric keys may be loaded with only their
+// public half, which keeps a retired key verifying the tokens it already
+// signed without letting it sign new ones.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+}
+
+// CanSign reports whether the private half of the key is available.
+func (k *Key) CanSign() bool {
+	return k.sign != nil
+}
+
+type Keyring struct {
+	keys    map[string]*Key
+	signing *Key
+}
+
+type KeyringConfig struct {
+	Dir        string // <kid>.pem for EdDSA/RS256 keys, <kid>.key for HS256 secrets
+	Secret     string // legacy HS256 secret, loaded as LegacyKeyID
+	PrivateKey string // PEM encoded key, loaded as SigningKID or DefaultKeyID
+	SigningKID string // defaults to DefaultKeyID if loaded, else LegacyKeyID
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{
+		keys: map[string]*Key{},
+	}
+}
+
+func LoadKeyring(cfg KeyringConfig) (*Keyring, error) {
+	kr := NewKeyring()
+
+	if cfg.Secret != "" {
+		key, err := NewHMACKey(LegacyKeyID, []byte(cfg.Secret))
+		if err != nil {
+			return nil, err
+		}
+		err = kr.Add(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Dir != "" {
+		entries, err := os.ReadDir(cfg.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read key directory: %v", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			ext := filepath.Ext(entry.Name())
+			if ext != ".pem" && ext != ".key" {
+				continue
+			}
+			kid := strings.TrimSuffix(entry.Name(), ext)
+
+			dat, err := os.ReadFile(filepath.Join(cfg.Dir, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("unable to read key %v: %v", kid, err)
+			}
+
+			var key *Key
+			if ext == ".key" {
+				key, err = NewHMACKey(kid, []byte(strings.TrimSpace(string(dat))))
+			} else {
+				key, err = ParseKeyPEM(kid, dat)
+			}
+			if err != nil {
+				return nil, err
+			}
+			err = kr.Add(key)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if cfg.PrivateKey != "" {
+		kid := cfg.SigningKID
+		if kid == "" {
+			kid = DefaultKeyID
+		}
+		key, err := ParseKeyPEM(kid, []byte(cfg.PrivateKey))
+		if err != nil {
+			return nil, err
+		}
+		err = kr.Add(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.SigningKID == "" {
+		cfg.SigningKID = DefaultKeyID
+		if _, ok := kr.keys[DefaultKeyID]; !ok && cfg.Secret != "" {
+			cfg.SigningKID = LegacyKeyID
+		}
+	}
+	err := kr.SetSigningKey(cfg.SigningKID)
+	if err != nil {
+		return nil, err
+	}
+	return kr, nil
+}
+
+func NewHMACKey(kid string, secret []byte) (*Key, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("key %v: empty secret", kid)
+	}
+	return &Key{
+		ID:     kid,
+		Method: jwt.SigningMethodHS256,
+		sign:   secret,
+		verify: secret,
+	}, nil
+}
+
+// ParseKeyPEM reads an Ed25519 or RSA key, private or public. The signing
+// method follows from the key type: EdDSA for Ed25519, RS256 for RSA.
+func ParseKeyPEM(kid string, dat []byte) (*Key, error) {
+	block, _ := pem.Decode(dat)
+	if block == nil {
+		return nil, fmt.Errorf("key %v: no PEM data found", kid)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %v: unsupported PEM block %q", kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %v: unable to parse: %v", kid, err)
+	}
+
+	key := &Key{ID: kid}
+	switch k := parsed.(type) {
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.sign = k
+		key.verify = k.Public()
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.verify = k
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+		key.sign = k
+		key.verify = &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+		key.verify = k
+	default:
+		return nil, fmt.Errorf("key %v: unsupported key type %T", kid, parsed)
+	}
+
+	if pub, ok := key.verify.(*rsa.PublicKey); ok && pub.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("key %v: RSA keys must be at least 2048 bits", kid)
+	}
+	return key, nil
+}
+
+func (kr *Keyring) Add(key *Key) error {
+	if _, ok := kr.keys[key.ID]; ok {
+		return fmt.Errorf("duplicate key id %v", key.ID)
+	}
+	kr.keys[key.ID] = key
+	return nil
+}
+
+func (kr *Keyring) SetSigningKey(kid string) error {
+	key, ok := kr.keys[kid]
+	if !ok {
+		return fmt.Errorf("signing key %v not found", kid)
+	}
+	if !key.CanSign() {
+		return fmt.Errorf("signing key %v has no private key", kid)
+	}
+	kr.signing = key
+	return nil
+}
+
+func (kr *Keyring) SigningKey() *Key {
+	return kr.signing
+}
+
+// Keys returns every key in the ring ordered by kid.
+func (kr *Keyring) Keys() []*Key {
+	keys := make([]*Key, 0, len(kr.keys))
+	for _, key := range kr.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+func (kr *Keyring) methods() []string {
+	seen := map[string]bool{}
+	var algs []string
+	for _, key := range kr.Keys() {
+		alg := key.Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// keyFunc picks the verification key named by the token's kid and pins the
+// algorithm to the one that key was loaded with.
+func (kr *Keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = LegacyKeyID
+	}
+	key, ok := kr.keys[kid]
+	if !ok && kid == DefaultKeyID {
+		// the SECRET key used to be called default, and still-valid tokens
+		// it signed under that name shouldn't be rejected
+		key, ok = kr.keys[LegacyKeyID]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v for key %v", token.Method.Alg(), kid)
+	}
+	return key.verify, nil
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	ScopeChirpsWrite  = "chirps:write"
+	ScopeChirpsDelete = "chirps:delete"
+	ScopeProfileRead  = "profile:read"
+	ScopeProfileWrite = "profile:write"
+)
+
+// Scopes lists everything a personal access token can be granted.
+var Scopes = []string{
+	ScopeChirpsWrite,
+	ScopeChirpsDelete,
+	ScopeProfileRead,
+	ScopeProfileWrite,
+}
+
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// PATPrefix marks personal access tokens so they can be told apart from JWTs
+// without a database lookup, and spotted by secret scanners.
+const PATPrefix = "chirpy_pat_"
+
+func MakePersonalAccessToken() (string, error) {
+	tkn, err := MakeToken()
+	if err != nil {
+		return "", fmt.Errorf("unable to create personal access token: %v", err)
+	}
+	return PATPrefix + tkn, nil
+}
+
+func IsPersonalAccessToken(tkn string) bool {
+	return strings.HasPrefix(tkn, PATPrefix)
+}
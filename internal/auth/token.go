@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"net/http"
@@ -22,16 +24,46 @@ func GetAPIKey(headers http.Header) (string, error) {
 	return strings.TrimSpace(Skey[1]), nil
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string) (string, error) {
-	claims := &jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
-		Subject:   userID.String(),
+// Issuer is the iss claim set on every token Chirpy signs.
+const Issuer = "chirpy"
+
+const mfaAudience = "chirpy-mfa"
+
+// AccessClaims is what an access token says about its holder. SessionID
+// names the login session it was issued for and is nil for tokens that
+// predate sessions.
+type AccessClaims struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid,omitempty"`
+}
+
+func MakeJWT(userID, sessionID uuid.UUID, keys *Keyring) (string, error) {
+	clms := &claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
+			Subject:   userID.String(),
+		},
+		SessionID: sessionID.String(),
 	}
 
-	tkn := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	jwt, err := tkn.SignedString([]byte(tokenSecret))
+	return signJWT(clms, keys)
+}
+
+func signJWT(claims jwt.Claims, keys *Keyring) (string, error) {
+	key := keys.SigningKey()
+	if key == nil {
+		return "", fmt.Errorf("error signing token: no signing key")
+	}
+	tkn := jwt.NewWithClaims(key.Method, claims)
+	tkn.Header["kid"] = key.ID
+	jwt, err := tkn.SignedString(key.sign)
 	if err != nil {
 		return "", fmt.Errorf("error signing token: %v", err)
 	}
@@ -39,24 +71,89 @@ func MakeJWT(userID uuid.UUID, tokenSecret string) (string, error) {
 }
 
 func MakeRefreshToken() (string, error) {
-	gen := make([]byte, 10)
-	_, err := rand.Read(gen)
+	tkn, err := MakeToken()
 	if err != nil {
 		return "", fmt.Errorf("unable to creat refresh token: %v", err)
 	}
 
+	return tkn, nil
+}
+
+// MakeToken returns 32 random bytes, hex encoded, for single-use opaque tokens
+// such as refresh and password reset tokens.
+func MakeToken() (string, error) {
+	gen := make([]byte, 32)
+	_, err := rand.Read(gen)
+	if err != nil {
+		return "", err
+	}
+
 	return hex.EncodeToString(gen), nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	tkn, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
-	})
+// HashToken returns the keyed hash an opaque token is stored and looked up by,
+// so the raw value never has to touch the database.
+func HashToken(token, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func ValidateJWT(tokenString string, keys *Keyring) (uuid.UUID, error) {
+	clms, err := ValidateAccessToken(tokenString, keys)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("unable to parse token: %v", err)
+		return uuid.Nil, err
 	}
+	return clms.UserID, nil
+}
 
-	clms := tkn.Claims.(*jwt.RegisteredClaims)
+func ValidateAccessToken(tokenString string, keys *Keyring) (AccessClaims, error) {
+	clms, err := parseJWT(tokenString, keys)
+	if err != nil {
+		return AccessClaims{}, err
+	}
+	// access tokens carry no audience; anything that does was issued for
+	// something narrower and must not be accepted here
+	if len(clms.Audience) != 0 {
+		return AccessClaims{}, fmt.Errorf("not an access token")
+	}
+	id, err := uuid.Parse(clms.Subject)
+	if err != nil {
+		return AccessClaims{}, fmt.Errorf("unable to parse id: %v", err)
+	}
+
+	access := AccessClaims{UserID: id}
+	if clms.SessionID != "" {
+		access.SessionID, err = uuid.Parse(clms.SessionID)
+		if err != nil {
+			return AccessClaims{}, fmt.Errorf("unable to parse session id: %v", err)
+		}
+	}
+	return access, nil
+}
+
+// MakeMFAToken is handed out after a correct password when the account has a
+// second factor. It only proves the first step and can't be used as an
+// access token.
+func MakeMFAToken(userID uuid.UUID, keys *Keyring) (string, error) {
+	claims := &jwt.RegisteredClaims{
+		Issuer:    Issuer,
+		Audience:  jwt.ClaimStrings{mfaAudience},
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+		Subject:   userID.String(),
+	}
+	return signJWT(claims, keys)
+}
+
+func ValidateMFAToken(tokenString string, keys *Keyring) (uuid.UUID, error) {
+	clms, err := parseJWT(tokenString, keys)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !clms.VerifyAudience(mfaAudience, true) {
+		return uuid.Nil, fmt.Errorf("not an mfa token")
+	}
 	id, err := uuid.Parse(clms.Subject)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("unable to parse id: %v", err)
@@ -65,6 +162,24 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	return id, nil
 }
 
+func parseJWT(tokenString string, keys *Keyring) (*claims, error) {
+	tkn, err := jwt.ParseWithClaims(
+		tokenString,
+		&claims{},
+		keys.keyFunc,
+		jwt.WithValidMethods(keys.methods()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse token: %v", err)
+	}
+
+	clms := tkn.Claims.(*claims)
+	if !clms.VerifyIssuer(Issuer, true) {
+		return nil, fmt.Errorf("unexpected token issuer %q", clms.Issuer)
+	}
+	return clms, nil
+}
+
 func GetBearerToken(headers http.Header) (string, error) {
 	Btkn := headers.Get("Authorization")
 	Stkn := strings.Split(Btkn, " ")
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RFC 6238 parameters, chosen to match what authenticator apps assume when an
+// otpauth URI leaves them out.
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	totpSkew   = 1
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func MakeTOTPSecret() (string, error) {
+	gen := make([]byte, 20)
+	_, err := rand.Read(gen)
+	if err != nil {
+		return "", fmt.Errorf("unable to create TOTP secret: %v", err)
+	}
+	return b32.EncodeToString(gen), nil
+}
+
+// TOTPURI is the otpauth:// URI authenticator apps read from a QR code.
+func TOTPURI(secret, account string) string {
+	label := url.PathEscape(Issuer + ":" + account)
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", Issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(totpDigits))
+	v.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// ValidateTOTP checks code against the time steps around now and returns the
+// step it matched. Steps at or before lastStep are refused so a code can't be
+// replayed once it has been accepted.
+func ValidateTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := b32.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func hotp(key []byte, counter int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, bin%1000000)
+}
+
+// MakeRecoveryCodes returns n single-use codes formatted as xxxxx-xxxxx.
+func MakeRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for range n {
+		gen := make([]byte, 5)
+		_, err := rand.Read(gen)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create recovery code: %v", err)
+		}
+		code := hex.EncodeToString(gen)
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode strips the formatting users tend to add or drop when
+// typing a code back in.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
+
+// SealSecret encrypts a secret that has to be recovered later, unlike tokens
+// which only ever need comparing and are hashed instead.
+func SealSecret(plaintext, key string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", fmt.Errorf("unable to seal secret: %v", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func OpenSecret(sealed, key string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	dat, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(dat) < gcm.NonceSize() {
+		return "", fmt.Errorf("unable to open secret: malformed data")
+	}
+	plain, err := gcm.Open(nil, dat[:gcm.NonceSize()], dat[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to open secret: %v", err)
+	}
+	return string(plain), nil
+}
+
+func newGCM(key string) (cipher.AEAD, error) {
+	// an empty key would seal everything under a constant anyone can derive
+	if key == "" {
+		return nil, fmt.Errorf("unable to create cipher: empty key")
+	}
+	sum := sha256.Sum256([]byte("chirpy secret box:" + key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
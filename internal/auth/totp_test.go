@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key from RFC 4226 and RFC 6238, "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226 appendix D
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	key := []byte("12345678901234567890")
+	for counter, code := range want {
+		got := hotp(key, int64(counter))
+		if got != code {
+			t.Errorf("hotp(%d) = %v, want %v", counter, got, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		now      int64
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		// RFC 6238 appendix B, cut down to six digits
+		{"rfc vector at 59", rfcSecret, "287082", 59, 0, 1, true},
+		{"rfc vector at 1111111109", rfcSecret, "081804", 1111111109, 0, 37037036, true},
+		{"rfc vector at 1234567890 keeps leading zeros", rfcSecret, "005924", 1234567890, 0, 41152263, true},
+		{"rfc vector at 2000000000", rfcSecret, "279037", 2000000000, 0, 66666666, true},
+
+		{"one step late", rfcSecret, "287082", 89, 0, 1, true},
+		{"one step early", rfcSecret, "287082", 29, 0, 1, true},
+		{"two steps late", rfcSecret, "287082", 119, 0, 0, false},
+		{"two steps early", rfcSecret, "359152", 29, 0, 0, false},
+		{"first second past the window", rfcSecret, "287082", 90, 0, 0, false},
+
+		{"replayed step", rfcSecret, "287082", 59, 1, 0, false},
+		{"step after an older one", rfcSecret, "287082", 89, 0, 1, true},
+		{"older step than last used", rfcSecret, "287082", 89, 2, 0, false},
+
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "287082", 59, 0, 1, true},
+		{"invalid secret", "not base32!", "287082", 59, 0, 0, false},
+		{"wrong code", rfcSecret, "123456", 59, 0, 0, false},
+		{"too short", rfcSecret, "28708", 59, 0, 0, false},
+		{"too long", rfcSecret, "2870820", 59, 0, 0, false},
+		{"leading zero dropped", rfcSecret, "5924", 1234567890, 0, 0, false},
+		{"empty", rfcSecret, "", 59, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(tt.secret, tt.code, time.Unix(tt.now, 0), tt.lastStep)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP() = %v, %v, want %v, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTOTPRoundTrip(t *testing.T) {
+	secret, err := MakeTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := b32.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q isn't unpadded base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Errorf("secret is %d bytes, want 20", len(key))
+	}
+
+	now := time.Now()
+	code := hotp(key, now.Unix()/totpPeriod)
+	step, ok := ValidateTOTP(secret, code, now, 0)
+	if !ok || step != now.Unix()/totpPeriod {
+		t.Errorf("ValidateTOTP() = %v, %v for a current code", step, ok)
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"abcde-12345", "abcde-12345"},
+		{"ABCDE-12345", "abcde-12345"},
+		{"abcde12345", "abcde-12345"},
+		{" abcde 12345 ", "abcde-12345"},
+		{"ab-cde-123-45", "abcde-12345"},
+		{"abcde-1234", "abcde1234"},
+	}
+	for _, tt := range tests {
+		got := NormalizeRecoveryCode(tt.in)
+		if got != tt.want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSealSecret(t *testing.T) {
+	sealed, err := SealSecret("JBSWY3DPEHPK3PXP", "key one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := OpenSecret(sealed, "key one")
+	if err != nil || plain != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("OpenSecret() = %q, %v", plain, err)
+	}
+	_, err = OpenSecret(sealed, "key two")
+	if err == nil {
+		t.Error("OpenSecret() with the wrong key succeeded")
+	}
+	_, err = SealSecret("JBSWY3DPEHPK3PXP", "")
+	if err == nil {
+		t.Error("SealSecret() with an empty key succeeded")
+	}
+	_, err = OpenSecret(sealed, "")
+	if err == nil {
+		t.Error("OpenSecret() with an empty key succeeded")
+	}
+}
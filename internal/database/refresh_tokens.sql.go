@@ -7,31 +7,64 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createRefToken = `-- name: CreateRefToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, family_id, parent_token)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
-    $3
+    $3,
+    $4,
+    $5
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, hashed
 `
 
 type CreateRefTokenParams struct {
-	Token     string
-	UserID    uuid.UUID
-	ExpiresAt time.Time
+	Token       string
+	UserID      uuid.UUID
+	ExpiresAt   time.Time
+	FamilyID    uuid.UUID
+	ParentToken sql.NullString
 }
 
 func (q *Queries) CreateRefToken(ctx context.Context, arg CreateRefTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, createRefToken, arg.Token, arg.UserID, arg.ExpiresAt)
+	row := q.db.QueryRowContext(ctx, createRefToken,
+		arg.Token,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.ParentToken,
+	)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.Hashed,
+	)
+	return i, err
+}
+
+const getLegacyRefreshToken = `-- name: GetLegacyRefreshToken :one
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, hashed FROM refresh_tokens
+WHERE token = $1 AND NOT hashed
+`
+
+func (q *Queries) GetLegacyRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getLegacyRefreshToken, token)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -40,13 +73,16 @@ func (q *Queries) CreateRefToken(ctx context.Context, arg CreateRefTokenParams)
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.Hashed,
 	)
 	return i, err
 }
 
 const getRefreshToken = `-- name: GetRefreshToken :one
-SELECT token, created_at, updated_at, user_id, expires_at, revoked_at FROM refresh_tokens
-WHERE token = $1
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, hashed FROM refresh_tokens
+WHERE token = $1 AND hashed
 `
 
 func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
@@ -59,10 +95,27 @@ func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshTok
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.Hashed,
 	)
 	return i, err
 }
 
+const revokeActiveToken = `-- name: RevokeActiveToken :execrows
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE token = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeActiveToken(ctx context.Context, token string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeActiveToken, token)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const revokeToken = `-- name: RevokeToken :exec
 UPDATE refresh_tokens
 SET updated_at = NOW(), revoked_at = NOW()
@@ -73,3 +126,72 @@ func (q *Queries) RevokeToken(ctx context.Context, token string) error {
 	_, err := q.db.ExecContext(ctx, revokeToken, token)
 	return err
 }
+
+const revokeTokenFamily = `-- name: RevokeTokenFamily :exec
+WITH revoked_session AS (
+    UPDATE sessions
+    SET revoked_at = NOW()
+    WHERE id = $1 AND revoked_at IS NULL
+)
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeTokenFamily, familyID)
+	return err
+}
+
+const revokeUserTokens = `-- name: RevokeUserTokens :exec
+WITH revoked_sessions AS (
+    UPDATE sessions
+    SET revoked_at = NOW()
+    WHERE user_id = $1 AND revoked_at IS NULL
+)
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserTokens, userID)
+	return err
+}
+
+const revokeUserTokensExcept = `-- name: RevokeUserTokensExcept :exec
+WITH revoked_sessions AS (
+    UPDATE sessions
+    SET revoked_at = NOW()
+    WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+)
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL
+`
+
+type RevokeUserTokensExceptParams struct {
+	UserID   uuid.UUID
+	FamilyID uuid.UUID
+}
+
+func (q *Queries) RevokeUserTokensExcept(ctx context.Context, arg RevokeUserTokensExceptParams) error {
+	_, err := q.db.ExecContext(ctx, revokeUserTokensExcept, arg.UserID, arg.FamilyID)
+	return err
+}
+
+const upgradeLegacyToken = `-- name: UpgradeLegacyToken :exec
+UPDATE refresh_tokens
+SET token = $1, hashed = true, updated_at = NOW()
+WHERE token = $2 AND NOT hashed
+`
+
+type UpgradeLegacyTokenParams struct {
+	Hash        string
+	LegacyToken string
+}
+
+func (q *Queries) UpgradeLegacyToken(ctx context.Context, arg UpgradeLegacyTokenParams) error {
+	_, err := q.db.ExecContext(ctx, upgradeLegacyToken, arg.Hash, arg.LegacyToken)
+	return err
+}
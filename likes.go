@@ -0,0 +1,189 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// likeChirp records that the caller likes a chirp. Liking a rechirp likes
+// the chirp it shares.
+func (cfg *apiConfig) likeChirp(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeChirpsWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	chirp, ok := cfg.likeTarget(w, r, uid)
+	if !ok {
+		return
+	}
+
+	n, err := cfg.db.LikeChirp(
+		r.Context(),
+		database.LikeChirpParams{
+			UserID:  uid,
+			ChirpID: chirp.ID,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to like chirp: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "you have already liked this chirp", 409)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unlikeChirp(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeChirpsWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	cid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, "invalid chirp id", 400)
+		return
+	}
+	// a like can still be taken back after a block, so this only follows
+	// rechirps rather than checking visibility
+	chirp, err := originalChirp(r.Context(), cfg.db, cid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to find chirp: %v", err), 404)
+		return
+	}
+
+	n, err := cfg.db.UnlikeChirp(
+		r.Context(),
+		database.UnlikeChirpParams{
+			UserID:  uid,
+			ChirpID: chirp.ID,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to unlike chirp: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "you haven't liked this chirp", 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) likeTarget(w http.ResponseWriter, r *http.Request, uid uuid.UUID) (database.Chirp, bool) {
+	cid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, "invalid chirp id", 400)
+		return database.Chirp{}, false
+	}
+	chirp, err := chirpFor(r.Context(), cfg.db, uid, cid)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, errChirpNotFound.Error(), 404)
+		return database.Chirp{}, false
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to find chirp: %v", err), 500)
+		return database.Chirp{}, false
+	}
+	return chirp, true
+}
+
+// listUserLikes pages through the chirps a user has liked, most recently
+// liked first by default. Cursors follow when the like happened rather than
+// when the chirp was posted.
+func (cfg *apiConfig) listUserLikes(w http.ResponseWriter, r *http.Request) {
+	uid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, "invalid user id", 400)
+		return
+	}
+	req, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
+		return
+	}
+	if r.URL.Query().Get("sort") == "" {
+		req.desc = true
+	}
+	vis, err := cfg.readerVisibility(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	if vis.blocks(uid) {
+		respondWithError(w, "user not found", 404)
+		return
+	}
+
+	var rows []database.ListUserLikesDescRow
+	if !req.ascending() {
+		rows, err = cfg.db.ListUserLikesDesc(
+			r.Context(),
+			database.ListUserLikesDescParams{
+				UserID:          uid,
+				HiddenIds:       vis.hidden,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+	} else {
+		var asc []database.ListUserLikesAscRow
+		asc, err = cfg.db.ListUserLikesAsc(
+			r.Context(),
+			database.ListUserLikesAscParams{
+				UserID:          uid,
+				HiddenIds:       vis.hidden,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+		for _, row := range asc {
+			rows = append(rows, database.ListUserLikesDescRow(row))
+		}
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve likes: %v", err), 500)
+		return
+	}
+
+	p := keysetPage(req, rows, func(row database.ListUserLikesDescRow) cursor {
+		return cursor{CreatedAt: row.LikedAt, ID: row.ID}
+	})
+	chrps := make([]database.Chirp, 0, len(p.items))
+	for _, row := range p.items {
+		chrps = append(chrps, database.Chirp{
+			ID:        row.ID,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Body:      row.Body,
+			UserID:    row.UserID,
+			InReplyTo: row.InReplyTo,
+			DeletedAt: row.DeletedAt,
+			RechirpOf: row.RechirpOf,
+			QuoteOf:   row.QuoteOf,
+			LikeCount: row.LikeCount,
+		})
+	}
+	chirps, err := cfg.chirpsFromDB(r.Context(), vis, chrps)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	resp := ChirpPage{
+		Chirps:     listed(chirps),
+		NextCursor: cursorString(p.next),
+		PrevCursor: cursorString(p.prev),
+	}
+	cfg.setLinkHeader(w, r, p.next, p.prev)
+	respondWithJSON(w, resp, 200)
+}
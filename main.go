@@ -19,6 +19,15 @@ func main() {
 	}
 	dbQ := database.New(db)
 
+	if len(os.Args) > 1 {
+		err = runCommand(db, dbQ, os.Args[1:])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Println("starting server")
-	StartServer(dbQ)
+	StartServer(db)
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type Mention struct {
+	UserId uuid.UUID `json:"user_id"`
+	Handle string    `json:"handle"`
+}
+
+var (
+	handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+	// like hashtags, an @ inside a word (an email address, say) isn't a mention
+	mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/@.])@([A-Za-z0-9_]{3,30})\b`)
+)
+
+func validHandle(handle string) bool {
+	return handlePattern.MatchString(handle)
+}
+
+// extractMentions returns the lowercased handles mentioned in body, without
+// duplicates.
+func extractMentions(body string) []string {
+	handles := []string{}
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		handle := strings.ToLower(m[1])
+		if seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+	}
+	return handles
+}
+
+// setChirpMentions resolves the handles in the chirp's body to users and
+// records them against it. Handles nobody has, and users on either side of a
+// block with the author, are ignored.
+func setChirpMentions(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	err := q.DeleteChirpMentions(ctx, chirp.ID)
+	if err != nil {
+		return fmt.Errorf("unable to clear mentions: %v", err)
+	}
+
+	handles := extractMentions(chirp.Body)
+	if len(handles) == 0 {
+		return nil
+	}
+	err = q.AddChirpMentions(
+		ctx,
+		database.AddChirpMentionsParams{
+			ChirpID:  chirp.ID,
+			Handles:  handles,
+			AuthorID: chirp.UserID,
+		})
+	if err != nil {
+		return fmt.Errorf("unable to save mentions: %v", err)
+	}
+	return nil
+}
+
+// linkChirp brings everything derived from a chirp's body up to date. Run it
+// in the same transaction that wrote the body.
+func linkChirp(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	err := setChirpHashtags(ctx, q, chirp.ID, chirp.Body)
+	if err != nil {
+		return err
+	}
+	return setChirpMentions(ctx, q, chirp)
+}
+
+func (cfg *apiConfig) listMentions(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileRead)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	req, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
+		return
+	}
+	vis, err := cfg.userVisibility(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	var rows []database.Chirp
+	if req.ascending() {
+		rows, err = cfg.db.ListMentionChirpsAsc(
+			r.Context(),
+			database.ListMentionChirpsAscParams{
+				UserID:          uid,
+				HiddenIds:       vis.hidden,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+	} else {
+		rows, err = cfg.db.ListMentionChirpsDesc(
+			r.Context(),
+			database.ListMentionChirpsDescParams{
+				UserID:          uid,
+				HiddenIds:       vis.hidden,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve chirps: %v", err), 500)
+		return
+	}
+
+	cfg.respondWithChirpPage(w, r, vis, req, rows)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxMutedWordLength = 100
+	maxMutedWords      = 200
+)
+
+const (
+	matchWord  = "word"
+	matchRegex = "regex"
+
+	actionHide     = "hide"
+	actionCollapse = "collapse"
+)
+
+// MutedWord is a phrase one user doesn't want to read. It only changes what
+// they are shown; what was posted is stored and served to everyone else as
+// is.
+type MutedWord struct {
+	Id        uuid.UUID  `json:"id"`
+	Phrase    string     `json:"phrase"`
+	Match     string     `json:"match"`
+	Action    string     `json:"action"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Expired   bool       `json:"expired,omitempty"`
+}
+
+// wordFilter is a muted word ready to be matched against chirp bodies.
+type wordFilter struct {
+	phrase string
+	re     *regexp.Regexp
+	hide   bool
+}
+
+// compileMutedWord builds the matcher for a muted word. Word matches ignore
+// case and only count where the phrase isn't part of a longer word, with any
+// run of whitespace matching the spaces in a phrase. Regexes are RE2, so a
+// user's pattern can't make matching take more than linear time.
+func compileMutedWord(phrase, match string) (*regexp.Regexp, error) {
+	switch match {
+	case matchWord:
+		words := strings.Fields(phrase)
+		for i := range words {
+			words[i] = regexp.QuoteMeta(words[i])
+		}
+		return regexp.Compile(`(?i)(?:^|[^\pL\pN_])` + strings.Join(words, `\s+`) + `(?:$|[^\pL\pN_])`)
+	case matchRegex:
+		re, err := regexp.Compile("(?i)" + phrase)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %v", err)
+		}
+		return re, nil
+	}
+	return nil, fmt.Errorf("match must be %q or %q", matchWord, matchRegex)
+}
+
+// filterWords marks the chirps that match the viewer's muted words, along with
+// the originals embedded in them. A rechirp has no body of its own, so it is
+// judged by the chirp it shares. Chirps are collapsed, keeping their body for
+// the reader to open; those matching a hide word are also flagged so listings
+// can leave them out. The viewer's own chirps are never filtered.
+func (v visibility) filterWords(chirps []Chirp) {
+	if len(v.words) == 0 {
+		return
+	}
+	for i := range chirps {
+		v.filterChirp(&chirps[i])
+		if chirps[i].Quoted != nil {
+			v.filterChirp(chirps[i].Quoted)
+		}
+		if o := chirps[i].Rechirped; o != nil {
+			v.filterChirp(o)
+			chirps[i].Collapsed = o.Collapsed
+			chirps[i].MutedWords = o.MutedWords
+			chirps[i].muted = o.muted
+		}
+	}
+}
+
+func (v visibility) filterChirp(c *Chirp) {
+	if c.Body == "" || (v.viewer.Valid && c.UserId == v.viewer.UUID) {
+		return
+	}
+	for _, f := range v.words {
+		if !f.re.MatchString(c.Body) {
+			continue
+		}
+		c.Collapsed = true
+		c.MutedWords = append(c.MutedWords, f.phrase)
+		if f.hide {
+			c.muted = true
+		}
+	}
+}
+
+// listed drops the chirps a muted word hides from a listing. Pages come back
+// short rather than being topped up, so cursors still line up with the rows
+// they were built from.
+func listed(chirps []Chirp) []Chirp {
+	shown := make([]Chirp, 0, len(chirps))
+	for _, c := range chirps {
+		if !c.muted {
+			shown = append(shown, c)
+		}
+	}
+	return shown
+}
+
+func mutedWordFromDB(row database.MutedWord) MutedWord {
+	word := MutedWord{
+		Id:        row.ID,
+		Phrase:    row.Phrase,
+		Match:     row.MatchType,
+		Action:    row.Action,
+		CreatedAt: row.CreatedAt,
+	}
+	if row.ExpiresAt.Valid {
+		word.ExpiresAt = &row.ExpiresAt.Time
+		word.Expired = !row.ExpiresAt.Time.After(time.Now())
+	}
+	return word
+}
+
+// listMutedWords returns all of the caller's muted words, newest first,
+// including expired ones so they can be renewed or cleaned up.
+func (cfg *apiConfig) listMutedWords(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileRead)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	rows, err := cfg.db.ListMutedWords(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve muted words: %v", err), 500)
+		return
+	}
+
+	resp := []MutedWord{}
+	for _, row := range rows {
+		resp = append(resp, mutedWordFromDB(row))
+	}
+	respondWithJSON(w, resp, 200)
+}
+
+// addMutedWord mutes a word or phrase for the caller. match defaults to
+// "word" and action to "collapse"; without expires_in_days it never expires.
+func (cfg *apiConfig) addMutedWord(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	type req struct {
+		Phrase        string `json:"phrase"`
+		Match         string `json:"match"`
+		Action        string `json:"action"`
+		ExpiresInDays int    `json:"expires_in_days"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return
+	}
+
+	phrase := strings.TrimSpace(Rdata.Phrase)
+	if phrase == "" || utf8.RuneCountInString(phrase) > maxMutedWordLength {
+		respondWithError(w, fmt.Sprintf("phrase must be 1 to %d characters", maxMutedWordLength), 400)
+		return
+	}
+	if Rdata.Match == "" {
+		Rdata.Match = matchWord
+	}
+	_, err = compileMutedWord(phrase, Rdata.Match)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
+		return
+	}
+	if Rdata.Action == "" {
+		Rdata.Action = actionCollapse
+	}
+	if Rdata.Action != actionHide && Rdata.Action != actionCollapse {
+		respondWithError(w, fmt.Sprintf("action must be %q or %q", actionHide, actionCollapse), 400)
+		return
+	}
+	if Rdata.ExpiresInDays < 0 {
+		respondWithError(w, "expires_in_days can't be negative", 400)
+		return
+	}
+
+	expires := sql.NullTime{}
+	if Rdata.ExpiresInDays > 0 {
+		expires = sql.NullTime{Time: time.Now().AddDate(0, 0, Rdata.ExpiresInDays), Valid: true}
+	}
+
+	n, err := cfg.db.CountActiveMutedWords(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to count muted words: %v", err), 500)
+		return
+	}
+	if n >= maxMutedWords {
+		respondWithError(w, fmt.Sprintf("you can't mute more than %d words", maxMutedWords), 400)
+		return
+	}
+
+	row, err := cfg.db.CreateMutedWord(
+		r.Context(),
+		database.CreateMutedWordParams{
+			UserID:    uid,
+			Phrase:    phrase,
+			MatchType: Rdata.Match,
+			Action:    Rdata.Action,
+			ExpiresAt: expires,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to mute word: %v", err), 500)
+		return
+	}
+	respondWithJSON(w, mutedWordFromDB(row), 201)
+}
+
+func (cfg *apiConfig) removeMutedWord(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r, auth.ScopeProfileWrite)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	wid, err := uuid.Parse(r.PathValue("wordID"))
+	if err != nil {
+		respondWithError(w, "invalid muted word id", 400)
+		return
+	}
+
+	n, err := cfg.db.DeleteMutedWord(
+		r.Context(),
+		database.DeleteMutedWordParams{
+			ID:     wid,
+			UserID: uid,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to remove muted word: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "muted word not found", 404)
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCompileMutedWord(t *testing.T) {
+	tests := []struct {
+		name   string
+		phrase string
+		match  string
+		body   string
+		want   bool
+	}{
+		{"exact word", "fornax", matchWord, "fornax", true},
+		{"ignores case", "fornax", matchWord, "I saw FORNAX today", true},
+		{"trailing punctuation", "fornax", matchWord, "what a fornax!", true},
+		{"leading punctuation", "fornax", matchWord, "(fornax) again", true},
+		{"hashtag", "fornax", matchWord, "#fornax", true},
+		{"mention", "fornax", matchWord, "@fornax hi", true},
+		{"apostrophe", "fornax", matchWord, "fornax's fault", true},
+		{"inside a longer word", "fornax", matchWord, "fornaxes everywhere", false},
+		{"suffix of a longer word", "fornax", matchWord, "superfornax", false},
+		{"joined by underscore", "fornax", matchWord, "fornax_bot", false},
+		{"joined by digit", "fornax", matchWord, "fornax2", false},
+		{"non-ascii letter counts as a word character", "café", matchWord, "cafés", false},
+		{"non-ascii phrase", "café", matchWord, "the café is shut", true},
+
+		{"phrase", "new york", matchWord, "off to new york", true},
+		{"phrase across extra spaces", "new york", matchWord, "off to new   york", true},
+		{"phrase across a newline", "new york", matchWord, "new\nyork", true},
+		{"phrase with spacing typed by the user", "  new   york ", matchWord, "new york", true},
+		{"phrase words out of order", "new york", matchWord, "york new", false},
+		{"phrase without the space", "new york", matchWord, "newyork", false},
+		{"phrase as part of a longer phrase", "new york", matchWord, "renew yorkshire", false},
+
+		{"metacharacters are literal", "c++", matchWord, "writing c++ today", true},
+		{"metacharacters aren't patterns", "a.c", matchWord, "abc", false},
+		{"dollar sign", "$gme", matchWord, "buying $gme", true},
+
+		{"regex", `spoil(er|s)`, matchRegex, "no spoilers please", true},
+		{"regex ignores case", `spoil(er|s)`, matchRegex, "SPOILERS", true},
+		{"regex matches anywhere", `ball`, matchRegex, "football", true},
+		{"regex anchors", `^breaking`, matchRegex, "not breaking news", false},
+		{"regex can turn case back on", `(?-i)Fornax`, matchRegex, "fornax", false},
+		{"regex no match", `\d{4}`, matchRegex, "123", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := compileMutedWord(tt.phrase, tt.match)
+			if err != nil {
+				t.Fatalf("compileMutedWord(%q, %q): %v", tt.phrase, tt.match, err)
+			}
+			if got := re.MatchString(tt.body); got != tt.want {
+				t.Errorf("%q matching %q = %v, want %v", tt.phrase, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileMutedWordErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		phrase string
+		match  string
+	}{
+		{"unbalanced regex", `(fornax`, matchRegex},
+		{"backreference", `(a)\1`, matchRegex},
+		{"unknown match type", "fornax", "glob"},
+		{"empty match type", "fornax", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileMutedWord(tt.phrase, tt.match)
+			if err == nil {
+				t.Errorf("compileMutedWord(%q, %q) succeeded, want an error", tt.phrase, tt.match)
+			}
+		})
+	}
+}
+
+func testVisibility(t *testing.T, viewer uuid.UUID, words ...wordFilter) visibility {
+	t.Helper()
+	for i := range words {
+		re, err := compileMutedWord(words[i].phrase, matchWord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		words[i].re = re
+	}
+	return visibility{
+		viewer: uuid.NullUUID{UUID: viewer, Valid: true},
+		words:  words,
+	}
+}
+
+func TestFilterWords(t *testing.T) {
+	viewer := uuid.New()
+	author := uuid.New()
+	vis := testVisibility(t, viewer,
+		wordFilter{phrase: "spoiler"},
+		wordFilter{phrase: "fornax", hide: true},
+	)
+
+	tests := []struct {
+		name          string
+		chirp         Chirp
+		wantCollapsed bool
+		wantMuted     bool
+		wantWords     []string
+	}{
+		{
+			name:  "no match",
+			chirp: Chirp{Body: "nothing to see", UserId: author},
+		},
+		{
+			name:          "collapse word",
+			chirp:         Chirp{Body: "big spoiler ahead", UserId: author},
+			wantCollapsed: true,
+			wantWords:     []string{"spoiler"},
+		},
+		{
+			name:          "hide word",
+			chirp:         Chirp{Body: "fornax!", UserId: author},
+			wantCollapsed: true,
+			wantMuted:     true,
+			wantWords:     []string{"fornax"},
+		},
+		{
+			name:          "both words",
+			chirp:         Chirp{Body: "fornax spoiler", UserId: author},
+			wantCollapsed: true,
+			wantMuted:     true,
+			wantWords:     []string{"spoiler", "fornax"},
+		},
+		{
+			name:  "viewer's own chirp",
+			chirp: Chirp{Body: "fornax spoiler", UserId: viewer},
+		},
+		{
+			name: "rechirp judged by the original",
+			chirp: Chirp{
+				UserId:    author,
+				Rechirped: &Chirp{Body: "fornax", UserId: uuid.New()},
+			},
+			wantCollapsed: true,
+			wantMuted:     true,
+			wantWords:     []string{"fornax"},
+		},
+		{
+			name: "viewer's rechirp of a muted chirp",
+			chirp: Chirp{
+				UserId:    viewer,
+				Rechirped: &Chirp{Body: "spoiler", UserId: author},
+			},
+			wantCollapsed: true,
+			wantWords:     []string{"spoiler"},
+		},
+		{
+			name: "quote judged by its own body",
+			chirp: Chirp{
+				Body:   "look at this",
+				UserId: author,
+				Quoted: &Chirp{Body: "fornax", UserId: uuid.New()},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chirps := []Chirp{tt.chirp}
+			vis.filterWords(chirps)
+			c := chirps[0]
+			if c.Collapsed != tt.wantCollapsed || c.muted != tt.wantMuted || !slices.Equal(c.MutedWords, tt.wantWords) {
+				t.Errorf("got collapsed=%v muted=%v words=%v, want collapsed=%v muted=%v words=%v",
+					c.Collapsed, c.muted, c.MutedWords, tt.wantCollapsed, tt.wantMuted, tt.wantWords)
+			}
+		})
+	}
+}
+
+func TestFilterWordsQuoted(t *testing.T) {
+	vis := testVisibility(t, uuid.New(), wordFilter{phrase: "fornax", hide: true})
+	chirps := []Chirp{{
+		Body:   "look at this",
+		UserId: uuid.New(),
+		Quoted: &Chirp{Body: "fornax", UserId: uuid.New()},
+	}}
+	vis.filterWords(chirps)
+	if q := chirps[0].Quoted; !q.Collapsed || !q.muted {
+		t.Errorf("quoted chirp collapsed=%v muted=%v, want both", q.Collapsed, q.muted)
+	}
+	if len(listed(chirps)) != 1 {
+		t.Error("a quote of a hidden chirp was left out of the listing")
+	}
+}
+
+func TestFilterWordsAnonymous(t *testing.T) {
+	chirps := []Chirp{{Body: "fornax", UserId: uuid.New()}}
+	visibility{}.filterWords(chirps)
+	if chirps[0].Collapsed || chirps[0].muted {
+		t.Error("an anonymous reader's chirps were filtered")
+	}
+}
+
+func TestListed(t *testing.T) {
+	chirps := []Chirp{
+		{Body: "a"},
+		{Body: "b", Collapsed: true},
+		{Body: "c", Collapsed: true, muted: true},
+		{Body: "d"},
+	}
+	var got []string
+	for _, c := range listed(chirps) {
+		got = append(got, c.Body)
+	}
+	if want := []string{"a", "b", "d"}; !slices.Equal(got, want) {
+		t.Errorf("listed() = %v, want %v", got, want)
+	}
+	if listed(nil) == nil {
+		t.Error("listed(nil) = nil, want an empty slice so it encodes as []")
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// cursor marks a position in a listing ordered by (created_at, id), or by
+// (rank, created_at, id) for search results. Prev cursors page back towards
+// the start of the listing rather than onwards.
+type cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+	Rank      float32   `json:"r,omitempty"`
+	Prev      bool      `json:"p,omitempty"`
+}
+
+func (c cursor) encode() string {
+	dat, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	c := cursor{}
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	err = json.Unmarshal(dat, &c)
+	if err != nil || c.ID == uuid.Nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	return c, nil
+}
+
+func (c *cursor) createdAt() sql.NullTime {
+	if c == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: c.CreatedAt, Valid: true}
+}
+
+func (c *cursor) id() uuid.NullUUID {
+	if c == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: c.ID, Valid: true}
+}
+
+// pageRequest is what a listing endpoint was asked for through ?limit=,
+// ?cursor= and ?sort=.
+type pageRequest struct {
+	limit  int
+	after  *cursor
+	desc   bool
+	isPrev bool
+}
+
+func parsePageRequest(r *http.Request) (pageRequest, error) {
+	req := pageRequest{limit: defaultPageLimit}
+	q := r.URL.Query()
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return req, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		req.limit = n
+	}
+	switch q.Get("sort") {
+	case "", "asc":
+	case "desc":
+		req.desc = true
+	default:
+		return req, fmt.Errorf("sort must be asc or desc")
+	}
+	if v := q.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil {
+			return req, err
+		}
+		req.after = &c
+		req.isPrev = c.Prev
+	}
+	return req, nil
+}
+
+// ascending reports which way the query has to walk from the cursor: a prev
+// page is fetched in the opposite order to the listing and flipped back.
+func (req pageRequest) ascending() bool {
+	return req.desc == req.isPrev
+}
+
+// fetchLimit asks for one row more than the page holds so we can tell
+// whether there's anything beyond it.
+func (req pageRequest) fetchLimit() int32 {
+	return int32(req.limit + 1)
+}
+
+type page[T any] struct {
+	items []T
+	next  *cursor
+	prev  *cursor
+}
+
+// keysetPage turns the rows fetched for req into a page in listing order and
+// works out the cursors either side of it.
+func keysetPage[T any](req pageRequest, rows []T, key func(T) cursor) page[T] {
+	more := len(rows) > req.limit
+	if more {
+		rows = rows[:req.limit]
+	}
+	if req.isPrev {
+		slices.Reverse(rows)
+	}
+
+	p := page[T]{items: rows}
+	if len(rows) == 0 {
+		return p
+	}
+	if more || req.isPrev {
+		c := key(rows[len(rows)-1])
+		p.next = &c
+	}
+	if (more && req.isPrev) || (!req.isPrev && req.after != nil) {
+		c := key(rows[0])
+		c.Prev = true
+		p.prev = &c
+	}
+	return p
+}
+
+// setLinkHeader advertises the neighbouring pages as RFC 8288 links, keeping
+// the rest of the request's query so filters carry over. Links point at
+// BASE_URL rather than the request's Host, which a cache could store forged.
+func (cfg *apiConfig) setLinkHeader(w http.ResponseWriter, r *http.Request, next, prev *cursor) {
+	var links []string
+	for _, l := range []struct {
+		rel string
+		c   *cursor
+	}{{"next", next}, {"prev", prev}} {
+		if l.c == nil {
+			continue
+		}
+		q := r.URL.Query()
+		q.Set("cursor", l.c.encode())
+		links = append(links, fmt.Sprintf("<%v%v?%v>; rel=%q", cfg.BaseURL, r.URL.Path, q.Encode(), l.rel))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+func cursorString(c *cursor) string {
+	if c == nil {
+		return ""
+	}
+	return c.encode()
+}
+
+// respondWithChirpPage writes one page of a (created_at, id) ordered chirp
+// listing in the shared paging format, less any chirps the reader's muted
+// words hide.
+func (cfg *apiConfig) respondWithChirpPage(w http.ResponseWriter, r *http.Request, vis visibility, req pageRequest, rows []database.Chirp) {
+	p := keysetPage(req, rows, func(c database.Chirp) cursor {
+		return cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+	})
+	chirps, err := cfg.chirpsFromDB(r.Context(), vis, p.items)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	resp := ChirpPage{
+		Chirps:     listed(chirps),
+		NextCursor: cursorString(p.next),
+		PrevCursor: cursorString(p.prev),
+	}
+	cfg.setLinkHeader(w, r, p.next, p.prev)
+	respondWithJSON(w, resp, 200)
+}
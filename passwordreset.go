@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/ScooballyD/chirpy/internal/mail"
+)
+
+const passwordResetTTL = 1 * time.Hour
+
+func (cfg *apiConfig) requestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	type req struct {
+		Email string `json:"email"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return
+	}
+
+	// the lookup and delivery happen in the background so the response looks
+	// the same whether or not the address belongs to an account
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		user, err := cfg.db.GetUser(ctx, Rdata.Email)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				fmt.Printf("error retrieving user info: %v", err)
+			}
+			return
+		}
+		err = cfg.sendPasswordReset(ctx, cfg.BaseURL, user)
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	w.WriteHeader(202)
+}
+
+// sendPasswordReset issues a fresh reset token for user, replacing any still
+// outstanding, and mails the link to their address.
+func (cfg *apiConfig) sendPasswordReset(ctx context.Context, base string, user database.User) error {
+	err := cfg.db.InvalidatePasswordResetTokens(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("unable to invalidate reset tokens: %v", err)
+	}
+
+	tkn, err := auth.MakeToken()
+	if err != nil {
+		return fmt.Errorf("unable to make reset token: %v", err)
+	}
+	err = cfg.db.CreatePasswordResetToken(
+		ctx,
+		database.CreatePasswordResetTokenParams{
+			Token:     auth.HashToken(tkn, cfg.TokenKey),
+			UserID:    user.ID,
+			ExpiresAt: time.Now().Add(passwordResetTTL),
+		})
+	if err != nil {
+		return fmt.Errorf("unable to save reset token: %v", err)
+	}
+
+	link := base + "/app/reset-password?token=" + url.QueryEscape(tkn)
+	err = cfg.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password for your Chirpy account.\n\n"+
+				"Use this link within %v to choose a new one:\n%v\n\n"+
+				"If this wasn't you, you can ignore this email.\n",
+			passwordResetTTL, link),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to send reset email: %v", err)
+	}
+	return nil
+}
+
+func (cfg *apiConfig) confirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	type req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return
+	}
+	if Rdata.Password == "" {
+		respondWithError(w, "password is required", 400)
+		return
+	}
+
+	hash := auth.HashToken(Rdata.Token, cfg.TokenKey)
+	tkn, err := cfg.db.GetPasswordResetToken(r.Context(), hash)
+	if err != nil {
+		respondWithError(w, "invalid or expired reset token", 400)
+		return
+	}
+	n, err := cfg.db.UsePasswordResetToken(r.Context(), hash)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to use reset token: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "invalid or expired reset token", 400)
+		return
+	}
+
+	hPass, err := cfg.Hasher.Hash(Rdata.Password)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	err = cfg.db.UpdateUserPassword(
+		r.Context(),
+		database.UpdateUserPasswordParams{
+			ID:             tkn.UserID,
+			HashedPassword: hPass,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to update password: %v", err), 500)
+		return
+	}
+
+	// whoever had the old password shouldn't keep a session through the reset
+	err = cfg.db.RevokeUserTokens(r.Context(), tkn.UserID)
+	if err != nil {
+		fmt.Printf("unable to revoke refresh tokens: %v", err)
+	}
+	err = cfg.db.InvalidatePasswordResetTokens(r.Context(), tkn.UserID)
+	if err != nil {
+		fmt.Printf("unable to invalidate reset tokens: %v", err)
+	}
+
+	w.WriteHeader(204)
+}
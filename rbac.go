@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Permissions are granted to roles in the role_permissions table; these are
+// the ones the server checks for.
+const (
+	permAdminMetrics   = "admin:metrics"
+	permAdminReset     = "admin:reset"
+	permAdminLockouts  = "admin:lockouts"
+	permChirpsModerate = "chirps:moderate"
+	permUsersManage    = "users:manage"
+	permUsersRoles     = "users:roles"
+)
+
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+var roles = []string{roleUser, roleModerator, roleAdmin}
+
+// requirePermission only lets next run for callers whose role has been
+// granted perm. Personal access tokens are never enough here, only a login.
+func (cfg *apiConfig) requirePermission(perm string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uid, err := cfg.authenticateLogin(r)
+		if err != nil {
+			fmt.Println(err)
+			respondWithAuthError(w, err)
+			return
+		}
+
+		ok, err := cfg.db.UserHasPermission(
+			r.Context(),
+			database.UserHasPermissionParams{
+				ID:         uid,
+				Permission: perm,
+			})
+		if err != nil {
+			respondWithError(w, fmt.Sprintf("unable to check permissions: %v", err), 500)
+			return
+		}
+		if !ok {
+			respondWithError(w, fmt.Sprintf("forbidden: missing permission %v", perm), 403)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// moderateChirp removes any chirp regardless of author.
+func (cfg *apiConfig) moderateChirp(w http.ResponseWriter, r *http.Request) {
+	cid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, "invalid chirp id", 400)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), cid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to find chirp: %v", err), 404)
+		return
+	}
+
+	err = cfg.removeChirp(r.Context(), chirp.ID)
+	if errors.Is(err, errChirpNotFound) {
+		respondWithError(w, "chirp has already been deleted", 404)
+		return
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to delete chirp: %v", err), 500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) setUserRole(w http.ResponseWriter, r *http.Request) {
+	uid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, "invalid user id", 400)
+		return
+	}
+
+	type req struct {
+		Role string `json:"role"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return
+	}
+	if !validRole(Rdata.Role) {
+		respondWithError(w, fmt.Sprintf("unknown role %q", Rdata.Role), 400)
+		return
+	}
+
+	n, err := cfg.db.SetUserRole(
+		r.Context(),
+		database.SetUserRoleParams{
+			ID:   uid,
+			Role: Rdata.Role,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to set role: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "user not found", 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func validRole(role string) bool {
+	return slices.Contains(roles, role)
+}
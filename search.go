@@ -0,0 +1,231 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type SearchResult struct {
+	Chirp
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type SearchPage struct {
+	Chirps     []SearchResult `json:"chirps"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	PrevCursor string         `json:"prev_cursor,omitempty"`
+}
+
+// ts_headline marks matches with these so the snippet can be escaped before
+// the real <mark> tags go in.
+var snippetMarks = strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>")
+
+// searchChirps ranks chirps against ?q=, most relevant first. Results page
+// the same way as the chirp listing, with the rank as part of the cursor.
+func (cfg *apiConfig) searchChirps(w http.ResponseWriter, r *http.Request) {
+	tsq, err := buildTSQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
+		return
+	}
+
+	req, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
+		return
+	}
+	// relevance only makes sense best first
+	req.desc = true
+	vis, err := cfg.readerVisibility(r)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	author := uuid.NullUUID{}
+	if Aid := r.URL.Query().Get("author_id"); Aid != "" {
+		id, err := uuid.Parse(Aid)
+		if err != nil {
+			respondWithError(w, "invalid author_id", 400)
+			return
+		}
+		author = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	since, err := timeParam(r, "since")
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
+		return
+	}
+	until, err := timeParam(r, "until")
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 400)
+		return
+	}
+
+	rank := sql.NullFloat64{}
+	if req.after != nil {
+		rank = sql.NullFloat64{Float64: float64(req.after.Rank), Valid: true}
+	}
+
+	var rows []database.SearchChirpsRow
+	if !req.ascending() {
+		rows, err = cfg.db.SearchChirps(
+			r.Context(),
+			database.SearchChirpsParams{
+				Query:           tsq,
+				AuthorID:        author,
+				Since:           since,
+				Until:           until,
+				HiddenIds:       vis.hidden,
+				CursorRank:      rank,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+	} else {
+		var rev []database.SearchChirpsReverseRow
+		rev, err = cfg.db.SearchChirpsReverse(
+			r.Context(),
+			database.SearchChirpsReverseParams{
+				Query:           tsq,
+				AuthorID:        author,
+				Since:           since,
+				Until:           until,
+				HiddenIds:       vis.hidden,
+				CursorRank:      rank,
+				CursorCreatedAt: req.after.createdAt(),
+				CursorID:        req.after.id(),
+				Lim:             req.fetchLimit(),
+			})
+		for _, row := range rev {
+			rows = append(rows, database.SearchChirpsRow(row))
+		}
+	}
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to search chirps: %v", err), 500)
+		return
+	}
+
+	p := keysetPage(req, rows, func(row database.SearchChirpsRow) cursor {
+		return cursor{CreatedAt: row.CreatedAt, ID: row.ID, Rank: row.Rank}
+	})
+	chirps := make([]Chirp, 0, len(p.items))
+	for _, row := range p.items {
+		chirps = append(chirps, Chirp{
+			Id:        row.ID,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Body:      row.Body,
+			UserId:    row.UserID,
+			LikeCount: row.LikeCount,
+		})
+	}
+	err = cfg.hydrate(r.Context(), vis, chirps)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	resp := SearchPage{
+		Chirps:     []SearchResult{},
+		NextCursor: cursorString(p.next),
+		PrevCursor: cursorString(p.prev),
+	}
+	for i, row := range p.items {
+		if chirps[i].muted {
+			continue
+		}
+		resp.Chirps = append(resp.Chirps, SearchResult{
+			Chirp:   chirps[i],
+			Rank:    row.Rank,
+			Snippet: snippetMarks.Replace(html.EscapeString(row.Snippet)),
+		})
+	}
+	cfg.setLinkHeader(w, r, p.next, p.prev)
+	respondWithJSON(w, resp, 200)
+}
+
+// buildTSQuery turns what a user types into a to_tsquery expression. Words
+// are ANDed together, "quoted words" must appear as a phrase, a trailing *
+// matches by prefix and a leading - excludes. Everything but letters and
+// digits is dropped, so nothing the user types can break the tsquery syntax.
+func buildTSQuery(q string) (string, error) {
+	var terms []string
+	positive := false
+	for {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+		if q == "" {
+			break
+		}
+
+		neg := strings.HasPrefix(q, "-")
+		if neg {
+			q = q[1:]
+		}
+		var raw string
+		if strings.HasPrefix(q, `"`) {
+			end := strings.Index(q[1:], `"`)
+			if end < 0 {
+				raw, q = q[1:], ""
+			} else {
+				raw, q = q[1:end+1], q[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(q, unicode.IsSpace)
+			if end < 0 {
+				end = len(q)
+			}
+			raw, q = q[:end], q[end:]
+		}
+
+		words := strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(words) == 0 {
+			continue
+		}
+		if strings.HasSuffix(raw, "*") {
+			words[len(words)-1] += ":*"
+		}
+		term := strings.Join(words, " <-> ")
+		if len(words) > 1 {
+			term = "(" + term + ")"
+		}
+		if neg {
+			term = "!" + term
+		} else {
+			positive = true
+		}
+		terms = append(terms, term)
+	}
+	if !positive {
+		return "", errors.New("q must contain at least one word to search for")
+	}
+	return strings.Join(terms, " & "), nil
+}
+
+// timeParam reads an optional RFC 3339 timestamp or plain date from the
+// query string.
+func timeParam(r *http.Request, name string) (sql.NullTime, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return sql.NullTime{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		t, err := time.Parse(layout, v)
+		if err == nil {
+			return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+		}
+	}
+	return sql.NullTime{}, fmt.Errorf("%v must be a date or RFC 3339 timestamp", name)
+}
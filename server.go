@@ -1,20 +1,56 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"sync/atomic"
+	"time"
 
+	"github.com/ScooballyD/chirpy/internal/auth"
 	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/ScooballyD/chirpy/internal/mail"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	db             *database.Queries
-	Platform       string
-	Secret         string
-	PolkaKey       string
+	fileserverHits       atomic.Int32
+	sqlDB                *sql.DB
+	db                   *database.Queries
+	Platform             string
+	BaseURL              string
+	RequireVerifiedEmail bool
+	Secret               string
+	Keys                 *auth.Keyring
+	Hasher               auth.Hasher
+	Mailer               mail.Mailer
+	TokenKey             string
+	EncryptionKey        string
+	dummyHash            string
+	PolkaKey             string
+	ChirpEditWindow      time.Duration
+}
+
+// inTx runs fn with queries bound to a single transaction, committing only if
+// fn succeeds.
+func (cfg *apiConfig) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = fn(cfg.db.WithTx(tx))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -42,28 +78,253 @@ func (cfg *apiConfig) resetHandler(w http.ResponseWriter, r *http.Request) {
 	cfg.db.ResetUsers(r.Context())
 }
 
-func StartServer(dbQ *database.Queries) {
+// passwordHasher builds the hashing policy new passwords are stored under.
+// Existing hashes made with weaker settings are upgraded on their next login.
+func passwordHasher() (auth.Hasher, error) {
+	switch alg := os.Getenv("PASSWORD_HASHER"); alg {
+	case "", "argon2id":
+		h := auth.DefaultArgon2id()
+		err := envUint("ARGON2_MEMORY", &h.Memory)
+		if err != nil {
+			return nil, err
+		}
+		err = envUint("ARGON2_ITERATIONS", &h.Iterations)
+		if err != nil {
+			return nil, err
+		}
+		p := uint32(h.Parallelism)
+		err = envUint("ARGON2_PARALLELISM", &p)
+		if err != nil {
+			return nil, err
+		}
+		if p == 0 || p > 255 {
+			return nil, fmt.Errorf("ARGON2_PARALLELISM must be between 1 and 255")
+		}
+		h.Parallelism = uint8(p)
+		return h, nil
+	case "bcrypt":
+		h := auth.BcryptHasher{Cost: 12}
+		if v := os.Getenv("BCRYPT_COST"); v != "" {
+			cost, err := strconv.Atoi(v)
+			if err != nil || cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+				return nil, fmt.Errorf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+			}
+			h.Cost = cost
+		}
+		return h, nil
+	default:
+		return nil, fmt.Errorf("unknown PASSWORD_HASHER %q", alg)
+	}
+}
+
+func newMailer() (mail.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Chirpy <no-reply@chirpy.local>"
+	}
+
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "", "log":
+		return mail.LogMailer{}, nil
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return &mail.FileMailer{Dir: dir, From: from}, nil
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp mail driver")
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return mail.NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", driver)
+	}
+}
+
+// configBaseURL reads the externally visible address of the server. Links in
+// mail are built from it, never from the request, so a forged Host header
+// can't send a reset token to someone else's domain. Outside dev it has to
+// be set.
+func configBaseURL(platform string) (string, error) {
+	v := os.Getenv("BASE_URL")
+	if v == "" {
+		if platform == "dev" {
+			return "http://localhost:8080", nil
+		}
+		return "", fmt.Errorf("BASE_URL is required")
+	}
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("BASE_URL must be an absolute http or https URL such as https://chirpy.example")
+	}
+	return strings.TrimSuffix(v, "/"), nil
+}
+
+func envUint(name string, dst *uint32) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.ParseUint(v, 10, 32)
+	if err != nil || n == 0 {
+		return fmt.Errorf("%v must be a positive integer", name)
+	}
+	*dst = uint32(n)
+	return nil
+}
+
+func StartServer(db *sql.DB) {
 	cfg := apiConfig{
-		fileserverHits: atomic.Int32{},
-		db:             dbQ,
-		Platform:       os.Getenv("PLATFORM"),
-		Secret:         os.Getenv("SECRET"),
-		PolkaKey:       os.Getenv("POLKA_KEY"),
+		fileserverHits:       atomic.Int32{},
+		sqlDB:                db,
+		db:                   database.New(db),
+		Platform:             os.Getenv("PLATFORM"),
+		RequireVerifiedEmail: os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true",
+		Secret:               os.Getenv("SECRET"),
+		TokenKey:             os.Getenv("TOKEN_HASH_KEY"),
+		EncryptionKey:        os.Getenv("ENCRYPTION_KEY"),
+		PolkaKey:             os.Getenv("POLKA_KEY"),
+	}
+	// refresh, reset and verification tokens, personal access tokens and
+	// recovery codes are all stored as HMACs under this key, so it can't be
+	// empty or shared with the JWT secret
+	if cfg.TokenKey == "" {
+		log.Fatalf("TOKEN_HASH_KEY is required")
+	}
+	if cfg.TokenKey == cfg.Secret {
+		log.Fatalf("TOKEN_HASH_KEY must not be the same as SECRET")
+	}
+	// TOTP secrets are sealed at rest under this one
+	if cfg.EncryptionKey == "" {
+		log.Fatalf("ENCRYPTION_KEY is required")
+	}
+	if cfg.EncryptionKey == cfg.Secret || cfg.EncryptionKey == cfg.TokenKey {
+		log.Fatalf("ENCRYPTION_KEY must not be the same as SECRET or TOKEN_HASH_KEY")
+	}
+
+	baseURL, err := configBaseURL(cfg.Platform)
+	if err != nil {
+		log.Fatalf("unable to configure links: %v", err)
+	}
+	cfg.BaseURL = baseURL
+
+	cfg.ChirpEditWindow = 15 * time.Minute
+	if v := os.Getenv("CHIRP_EDIT_WINDOW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("CHIRP_EDIT_WINDOW must be a positive duration such as 15m")
+		}
+		cfg.ChirpEditWindow = d
 	}
+
+	keys, err := auth.LoadKeyring(auth.KeyringConfig{
+		Dir:        os.Getenv("JWT_KEYS_DIR"),
+		Secret:     cfg.Secret,
+		PrivateKey: os.Getenv("JWT_PRIVATE_KEY"),
+		SigningKID: os.Getenv("JWT_SIGNING_KID"),
+	})
+	if err != nil {
+		log.Fatalf("unable to load JWT keys: %v", err)
+	}
+	cfg.Keys = keys
+
+	hasher, err := passwordHasher()
+	if err != nil {
+		log.Fatalf("unable to configure password hashing: %v", err)
+	}
+	cfg.Hasher = hasher
+	cfg.dummyHash, err = hasher.Hash("chirpy-dummy-password")
+	if err != nil {
+		log.Fatalf("unable to configure password hashing: %v", err)
+	}
+
+	mailer, err := newMailer()
+	if err != nil {
+		log.Fatalf("unable to configure mail delivery: %v", err)
+	}
+	cfg.Mailer = mailer
+
 	mux := http.NewServeMux()
 	mux.Handle("/app/", http.StripPrefix("/app", cfg.middlewareMetricsInc(http.FileServer(http.Dir(".")))))
-	mux.HandleFunc("GET /admin/metrics", cfg.metricsHandler)
-	mux.HandleFunc("POST /admin/reset", cfg.resetHandler)
+	mux.HandleFunc("GET /.well-known/jwks.json", cfg.jwksHandler)
+	mux.HandleFunc("GET /.well-known/openid-configuration", cfg.discoveryHandler)
+	mux.HandleFunc("GET /admin/metrics", cfg.requirePermission(permAdminMetrics, cfg.metricsHandler))
+	mux.HandleFunc("POST /admin/reset", cfg.requirePermission(permAdminReset, cfg.resetHandler))
+	mux.HandleFunc("GET /admin/lockouts", cfg.requirePermission(permAdminLockouts, cfg.listLockouts))
+	mux.HandleFunc("DELETE /admin/lockouts/{subject}", cfg.requirePermission(permAdminLockouts, cfg.clearLockout))
+	mux.HandleFunc("GET /admin/users", cfg.requirePermission(permUsersManage, cfg.listUsers))
+	mux.HandleFunc("GET /admin/users/{userID}", cfg.requirePermission(permUsersManage, cfg.getUser))
+	mux.HandleFunc("DELETE /admin/users/{userID}", cfg.requirePermission(permUsersManage, cfg.deleteUser))
+	mux.HandleFunc("PUT /admin/users/{userID}/chirpy-red", cfg.requirePermission(permUsersManage, cfg.setChirpyRed))
+	mux.HandleFunc("POST /admin/users/{userID}/password-reset", cfg.requirePermission(permUsersManage, cfg.forcePasswordReset))
+	mux.HandleFunc("PUT /admin/users/{userID}/role", cfg.requirePermission(permUsersRoles, cfg.setUserRole))
+	mux.HandleFunc("POST /admin/users/{userID}/suspension", cfg.requirePermission(permUsersManage, cfg.suspendUser))
+	mux.HandleFunc("DELETE /admin/users/{userID}/suspension", cfg.requirePermission(permUsersManage, cfg.unsuspendUser))
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.deleteChirp)
 	mux.HandleFunc("GET /api/chirps", cfg.getChirps)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", cfg.getChirps)
+	mux.HandleFunc("GET /api/chirps/{chirpID}", cfg.getChirp)
 	mux.HandleFunc("POST /api/chirps", cfg.validateChirpHandler)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", cfg.editChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/likes", cfg.likeChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/likes", cfg.unlikeChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", cfg.rechirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", cfg.undoRechirp)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", cfg.listChirpRevisions)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", cfg.getThread)
+	mux.HandleFunc("POST /api/2fa/confirm", cfg.confirmTOTP)
+	mux.HandleFunc("POST /api/2fa/disable", cfg.disableTOTP)
+	mux.HandleFunc("POST /api/2fa/enroll", cfg.enrollTOTP)
+	mux.HandleFunc("GET /api/hashtags", cfg.autocompleteHashtags)
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", cfg.getHashtagChirps)
 	mux.HandleFunc("POST /api/login", cfg.loginUser)
+	mux.HandleFunc("POST /api/login/mfa", cfg.loginMFA)
+	mux.HandleFunc("DELETE /api/moderation/chirps/{chirpID}", cfg.requirePermission(permChirpsModerate, cfg.moderateChirp))
+	mux.HandleFunc("POST /api/password-reset/confirm", cfg.confirmPasswordReset)
+	mux.HandleFunc("POST /api/password-reset/request", cfg.requestPasswordReset)
 	mux.HandleFunc("POST /api/polka/webhooks", cfg.upgradeUser)
 	mux.HandleFunc("POST /api/refresh", cfg.validateRefreshToken)
 	mux.HandleFunc("POST /api/revoke", cfg.revokeRefreshToken)
+	mux.HandleFunc("GET /api/search/chirps", cfg.searchChirps)
+	mux.HandleFunc("GET /api/sessions", cfg.listSessions)
+	mux.HandleFunc("DELETE /api/sessions", cfg.revokeAllSessions)
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", cfg.revokeSession)
+	mux.HandleFunc("GET /api/timeline/home", cfg.homeTimeline)
+	mux.HandleFunc("GET /api/tokens", cfg.listAccessTokens)
+	mux.HandleFunc("POST /api/tokens", cfg.createAccessToken)
+	mux.HandleFunc("DELETE /api/tokens/{tokenID}", cfg.revokeAccessToken)
 	mux.HandleFunc("POST /api/users", cfg.createUser)
 	mux.HandleFunc("PUT /api/users", cfg.updateUser)
+	mux.HandleFunc("GET /api/users/me/blocks", cfg.listBlocks)
+	mux.HandleFunc("GET /api/users/me/bookmarks", cfg.listBookmarks)
+	mux.HandleFunc("POST /api/users/me/bookmarks", cfg.addBookmark)
+	mux.HandleFunc("PUT /api/users/me/bookmarks/{chirpID}", cfg.moveBookmark)
+	mux.HandleFunc("DELETE /api/users/me/bookmarks/{chirpID}", cfg.removeBookmark)
+	mux.HandleFunc("GET /api/users/me/bookmarks/folders", cfg.listBookmarkFolders)
+	mux.HandleFunc("POST /api/users/me/bookmarks/folders", cfg.createBookmarkFolder)
+	mux.HandleFunc("PUT /api/users/me/bookmarks/folders/{folderID}", cfg.renameBookmarkFolder)
+	mux.HandleFunc("DELETE /api/users/me/bookmarks/folders/{folderID}", cfg.deleteBookmarkFolder)
+	mux.HandleFunc("GET /api/users/me/mentions", cfg.listMentions)
+	mux.HandleFunc("GET /api/users/me/muted-words", cfg.listMutedWords)
+	mux.HandleFunc("POST /api/users/me/muted-words", cfg.addMutedWord)
+	mux.HandleFunc("DELETE /api/users/me/muted-words/{wordID}", cfg.removeMutedWord)
+	mux.HandleFunc("GET /api/users/me/mutes", cfg.listMutes)
+	mux.HandleFunc("DELETE /api/users/{userID}/block", cfg.unblockUser)
+	mux.HandleFunc("POST /api/users/{userID}/block", cfg.blockUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", cfg.unfollowUser)
+	mux.HandleFunc("POST /api/users/{userID}/follow", cfg.followUser)
+	mux.HandleFunc("GET /api/users/{userID}/followers", cfg.listFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", cfg.listFollowing)
+	mux.HandleFunc("GET /api/users/{userID}/likes", cfg.listUserLikes)
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", cfg.unmuteUser)
+	mux.HandleFunc("POST /api/users/{userID}/mute", cfg.muteUser)
+	mux.HandleFunc("POST /api/users/verify", cfg.verifyEmail)
+	mux.HandleFunc("POST /api/users/verify/resend", cfg.resendVerification)
 
 	srv := http.Server{
 		Handler: mux,
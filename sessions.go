@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+type Session struct {
+	Id         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Current    bool      `json:"current"`
+}
+
+func (cfg *apiConfig) listSessions(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticateLogin(r)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+	current := cfg.currentSession(r)
+
+	rows, err := cfg.db.ListActiveSessions(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve sessions: %v", err), 500)
+		return
+	}
+
+	resp := []Session{}
+	for _, row := range rows {
+		resp = append(resp, Session{
+			Id:         row.ID,
+			CreatedAt:  row.CreatedAt,
+			LastUsedAt: row.LastUsedAt,
+			UserAgent:  row.UserAgent,
+			IP:         row.Ip,
+			Current:    row.ID == current,
+		})
+	}
+	respondWithJSON(w, resp, 200)
+}
+
+func (cfg *apiConfig) revokeSession(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticateLogin(r)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, "invalid session id", 400)
+		return
+	}
+	session, err := cfg.db.GetSession(r.Context(), id)
+	if err != nil || session.UserID != uid {
+		respondWithError(w, "session not found", 404)
+		return
+	}
+
+	err = cfg.db.RevokeTokenFamily(r.Context(), session.ID)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to revoke session: %v", err), 500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// revokeAllSessions signs the user out everywhere, including the session
+// making the request.
+func (cfg *apiConfig) revokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticateLogin(r)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	err = cfg.db.RevokeUserTokens(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to revoke sessions: %v", err), 500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// currentSession is the session the request's access token was issued for,
+// or uuid.Nil when it came from a personal access token or an older JWT.
+func (cfg *apiConfig) currentSession(r *http.Request) uuid.UUID {
+	tkn, err := auth.GetBearerToken(r.Header)
+	if err != nil || auth.IsPersonalAccessToken(tkn) {
+		return uuid.Nil
+	}
+	clms, err := auth.ValidateAccessToken(tkn, cfg.Keys)
+	if err != nil {
+		return uuid.Nil
+	}
+	return clms.SessionID
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func userAgent(r *http.Request) string {
+	ua := r.UserAgent()
+	if len(ua) > 512 {
+		ua = ua[:512]
+	}
+	return ua
+}
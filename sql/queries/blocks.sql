@@ -0,0 +1,46 @@
+-- name: BlockUser :execrows
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :execrows
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: ListBlocks :many
+SELECT users.id, users.handle, blocks.created_at FROM blocks
+JOIN users ON users.id = blocks.blocked_id
+WHERE blocks.blocker_id = $1
+ORDER BY blocks.created_at DESC, blocks.blocked_id DESC;
+
+-- name: MuteUser :execrows
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :execrows
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: ListMutes :many
+SELECT users.id, users.handle, mutes.created_at FROM mutes
+JOIN users ON users.id = mutes.muted_id
+WHERE mutes.muter_id = $1
+ORDER BY mutes.created_at DESC, mutes.muted_id DESC;
+
+-- name: IsBlockedBetween :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = @a AND blocked_id = @b) OR (blocker_id = @b AND blocked_id = @a)
+);
+
+-- name: ListHiddenAuthors :many
+SELECT blocked_id AS author_id, true AS blocked FROM blocks WHERE blocker_id = @user_id
+UNION
+SELECT blocker_id, true FROM blocks WHERE blocked_id = @user_id
+UNION
+SELECT muted_id, false FROM mutes WHERE muter_id = @user_id;
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = @a AND followee_id = @b) OR (follower_id = @b AND followee_id = @a);
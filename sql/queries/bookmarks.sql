@@ -0,0 +1,65 @@
+-- name: CreateBookmarkFolder :one
+INSERT INTO bookmark_folders (id, user_id, name, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetBookmarkFolder :one
+SELECT * FROM bookmark_folders
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListBookmarkFolders :many
+SELECT bookmark_folders.*, COUNT(bookmarks.chirp_id) AS bookmark_count FROM bookmark_folders
+LEFT JOIN bookmarks ON bookmarks.folder_id = bookmark_folders.id
+WHERE bookmark_folders.user_id = $1
+GROUP BY bookmark_folders.id
+ORDER BY LOWER(bookmark_folders.name);
+
+-- name: RenameBookmarkFolder :execrows
+UPDATE bookmark_folders
+SET name = $3
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteBookmarkFolder :execrows
+DELETE FROM bookmark_folders
+WHERE id = $1 AND user_id = $2;
+
+-- name: AddBookmark :execrows
+INSERT INTO bookmarks (user_id, chirp_id, folder_id, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: MoveBookmark :execrows
+UPDATE bookmarks
+SET folder_id = $3
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: DeleteBookmark :execrows
+DELETE FROM bookmarks
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: ListBookmarksAsc :many
+SELECT chirps.*, bookmarks.folder_id, bookmarks.created_at AS bookmarked_at FROM bookmarks
+JOIN chirps ON chirps.id = bookmarks.chirp_id
+WHERE bookmarks.user_id = @user_id
+    AND (NOT @filter_folder::boolean OR bookmarks.folder_id IS NOT DISTINCT FROM sqlc.narg(folder_id)::uuid)
+    AND chirps.user_id <> ALL(@hidden_ids::uuid[])
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (bookmarks.created_at, bookmarks.chirp_id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY bookmarks.created_at ASC, bookmarks.chirp_id ASC
+LIMIT @lim;
+
+-- name: ListBookmarksDesc :many
+SELECT chirps.*, bookmarks.folder_id, bookmarks.created_at AS bookmarked_at FROM bookmarks
+JOIN chirps ON chirps.id = bookmarks.chirp_id
+WHERE bookmarks.user_id = @user_id
+    AND (NOT @filter_folder::boolean OR bookmarks.folder_id IS NOT DISTINCT FROM sqlc.narg(folder_id)::uuid)
+    AND chirps.user_id <> ALL(@hidden_ids::uuid[])
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (bookmarks.created_at, bookmarks.chirp_id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY bookmarks.created_at DESC, bookmarks.chirp_id DESC
+LIMIT @lim;
@@ -1,26 +1,116 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, quote_of)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
     $2
 )
 RETURNING *;
 
--- name: GetChirps :many
+-- name: DeleteRechirp :execrows
+DELETE FROM chirps
+WHERE user_id = $1 AND rechirp_of = $2 AND deleted_at IS NULL;
+
+-- name: DeleteRechirpsOf :exec
+DELETE FROM chirps
+WHERE rechirp_of = $1;
+
+-- name: CountRechirps :many
+SELECT rechirp_of, COUNT(*) AS rechirp_count FROM chirps
+WHERE rechirp_of = ANY(@chirp_ids::uuid[]) AND deleted_at IS NULL
+GROUP BY rechirp_of;
+
+-- name: GetChirpsByIDs :many
 SELECT * FROM chirps
-ORDER BY created_at ASC;
+WHERE id = ANY(@ids::uuid[]);
 
--- name: GetChirpsDesc :many
+-- name: ListChirpsByID :many
 SELECT * FROM chirps
-ORDER BY created_at DESC;
+WHERE deleted_at IS NULL
+    AND (sqlc.narg(after_id)::uuid IS NULL OR id > sqlc.narg(after_id))
+ORDER BY id
+LIMIT sqlc.arg(lim);
+
+-- name: ListChirpsAsc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+    AND (sqlc.narg(author_id)::uuid IS NULL OR user_id = sqlc.narg(author_id))
+    AND user_id <> ALL(@hidden_ids::uuid[])
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (created_at, id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(lim);
+
+-- name: ListChirpsDesc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+    AND (sqlc.narg(author_id)::uuid IS NULL OR user_id = sqlc.narg(author_id))
+    AND user_id <> ALL(@hidden_ids::uuid[])
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(lim);
 
 -- name: GetChirp :one
 SELECT * FROM chirps
 WHERE id = $1;
 
--- name: DeleteChirp :exec
-DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+-- name: TombstoneChirp :execrows
+UPDATE chirps
+SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: CountChirpReplies :many
+SELECT in_reply_to, COUNT(*) AS reply_count FROM chirps
+WHERE in_reply_to = ANY(@chirp_ids::uuid[]) AND deleted_at IS NULL
+GROUP BY in_reply_to;
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT chirps.*, 1 AS depth FROM chirps
+    WHERE chirps.id = (SELECT parent.in_reply_to FROM chirps AS parent WHERE parent.id = @id)
+    UNION ALL
+    SELECT chirps.*, ancestors.depth + 1 FROM chirps
+    JOIN ancestors ON chirps.id = ancestors.in_reply_to
+)
+SELECT * FROM ancestors
+ORDER BY depth DESC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT chirps.*, 1 AS depth FROM chirps
+    WHERE chirps.in_reply_to = @id
+    UNION ALL
+    SELECT chirps.*, descendants.depth + 1 FROM chirps
+    JOIN descendants ON chirps.in_reply_to = descendants.id
+    WHERE descendants.depth < @max_depth::int
+)
+SELECT * FROM descendants
+ORDER BY created_at ASC, id ASC
+LIMIT @lim;
+-- name: GetChirpForUpdate :one
+SELECT * FROM chirps
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,85 @@
+-- name: FollowUser :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :execrows
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollowCounts :one
+SELECT
+    (SELECT COUNT(*) FROM follows WHERE followee_id = @user_id) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follower_id = @user_id) AS following_count;
+
+-- name: ListFollowersAsc :many
+SELECT users.id, users.handle, follows.created_at AS followed_at FROM follows
+JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = @user_id
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (follows.created_at, follows.follower_id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY follows.created_at ASC, follows.follower_id ASC
+LIMIT @lim;
+
+-- name: ListFollowersDesc :many
+SELECT users.id, users.handle, follows.created_at AS followed_at FROM follows
+JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = @user_id
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (follows.created_at, follows.follower_id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY follows.created_at DESC, follows.follower_id DESC
+LIMIT @lim;
+
+-- name: ListFollowingAsc :many
+SELECT users.id, users.handle, follows.created_at AS followed_at FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = @user_id
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (follows.created_at, follows.followee_id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY follows.created_at ASC, follows.followee_id ASC
+LIMIT @lim;
+
+-- name: ListFollowingDesc :many
+SELECT users.id, users.handle, follows.created_at AS followed_at FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = @user_id
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (follows.created_at, follows.followee_id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY follows.created_at DESC, follows.followee_id DESC
+LIMIT @lim;
+
+-- name: ListHomeTimelineAsc :many
+SELECT timeline.* FROM (
+    SELECT followee_id AS author_id FROM follows
+    WHERE follower_id = @user_id AND followee_id <> ALL(@hidden_ids::uuid[])
+    UNION ALL
+    SELECT @user_id::uuid
+) authors
+CROSS JOIN LATERAL (
+    SELECT chirps.* FROM chirps
+    WHERE chirps.user_id = authors.author_id AND chirps.deleted_at IS NULL
+        AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+            OR (chirps.created_at, chirps.id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+    ORDER BY chirps.created_at ASC, chirps.id ASC
+    LIMIT @lim
+) timeline
+ORDER BY timeline.created_at ASC, timeline.id ASC
+LIMIT @lim;
+
+-- name: ListHomeTimelineDesc :many
+SELECT timeline.* FROM (
+    SELECT followee_id AS author_id FROM follows
+    WHERE follower_id = @user_id AND followee_id <> ALL(@hidden_ids::uuid[])
+    UNION ALL
+    SELECT @user_id::uuid
+) authors
+CROSS JOIN LATERAL (
+    SELECT chirps.* FROM chirps
+    WHERE chirps.user_id = authors.author_id AND chirps.deleted_at IS NULL
+        AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+            OR (chirps.created_at, chirps.id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+    ORDER BY chirps.created_at DESC, chirps.id DESC
+    LIMIT @lim
+) timeline
+ORDER BY timeline.created_at DESC, timeline.id DESC
+LIMIT @lim;
@@ -0,0 +1,45 @@
+-- name: AddChirpHashtags :exec
+WITH tags AS (
+    INSERT INTO hashtags (id, name, created_at)
+    SELECT gen_random_uuid(), name, NOW()
+    FROM unnest(@names::text[]) AS name
+    ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+    RETURNING id
+)
+INSERT INTO chirp_hashtags (chirp_id, hashtag_id)
+SELECT @chirp_id::uuid, id FROM tags
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: ListHashtagChirpsAsc :many
+SELECT chirps.* FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+WHERE hashtags.name = @name
+    AND chirps.user_id <> ALL(@hidden_ids::uuid[])
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT @lim;
+
+-- name: ListHashtagChirpsDesc :many
+SELECT chirps.* FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+WHERE hashtags.name = @name
+    AND chirps.user_id <> ALL(@hidden_ids::uuid[])
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT @lim;
+
+-- name: AutocompleteHashtags :many
+SELECT hashtags.name, COUNT(*) AS chirp_count FROM hashtags
+JOIN chirp_hashtags ON chirp_hashtags.hashtag_id = hashtags.id
+WHERE hashtags.name LIKE @prefix::text || '%'
+GROUP BY hashtags.id, hashtags.name
+ORDER BY chirp_count DESC, hashtags.name ASC
+LIMIT @lim;
@@ -0,0 +1,32 @@
+-- name: LikeChirp :execrows
+INSERT INTO likes (user_id, chirp_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :execrows
+DELETE FROM likes
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: ListLikedChirpIDs :many
+SELECT chirp_id FROM likes
+WHERE user_id = @user_id AND chirp_id = ANY(@chirp_ids::uuid[]);
+
+-- name: ListUserLikesAsc :many
+SELECT chirps.*, likes.created_at AS liked_at FROM likes
+JOIN chirps ON chirps.id = likes.chirp_id
+WHERE likes.user_id = @user_id AND chirps.deleted_at IS NULL
+    AND chirps.user_id <> ALL(@hidden_ids::uuid[])
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (likes.created_at, likes.chirp_id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY likes.created_at ASC, likes.chirp_id ASC
+LIMIT @lim;
+
+-- name: ListUserLikesDesc :many
+SELECT chirps.*, likes.created_at AS liked_at FROM likes
+JOIN chirps ON chirps.id = likes.chirp_id
+WHERE likes.user_id = @user_id AND chirps.deleted_at IS NULL
+    AND chirps.user_id <> ALL(@hidden_ids::uuid[])
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (likes.created_at, likes.chirp_id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY likes.created_at DESC, likes.chirp_id DESC
+LIMIT @lim;
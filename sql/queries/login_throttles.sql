@@ -0,0 +1,34 @@
+-- name: RecordLoginAttempt :one
+INSERT INTO login_throttles (subject, failures, last_failure_at, blocked_until)
+VALUES (
+    $1,
+    1,
+    NOW(),
+    NOW()
+)
+ON CONFLICT (subject) DO UPDATE
+SET failures = CASE
+        WHEN login_throttles.last_failure_at < NOW() - INTERVAL '24 hours' THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    last_failure_at = NOW()
+RETURNING failures, blocked_until;
+
+-- name: ForgiveLoginAttempt :exec
+UPDATE login_throttles
+SET failures = failures - 1
+WHERE subject = $1 AND failures > 0;
+
+-- name: BlockLogin :exec
+UPDATE login_throttles
+SET blocked_until = $2
+WHERE subject = $1;
+
+-- name: ClearLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE subject = $1;
+
+-- name: ListLoginLockouts :many
+SELECT * FROM login_throttles
+WHERE blocked_until > NOW()
+ORDER BY blocked_until DESC;
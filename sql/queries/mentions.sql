@@ -0,0 +1,40 @@
+-- name: AddChirpMentions :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+SELECT @chirp_id::uuid, users.id FROM users
+WHERE LOWER(users.handle) = ANY(@handles::text[])
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks
+        WHERE (blocks.blocker_id = users.id AND blocks.blocked_id = @author_id)
+            OR (blocks.blocker_id = @author_id AND blocks.blocked_id = users.id)
+    )
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
+
+-- name: ListChirpMentions :many
+SELECT chirp_mentions.chirp_id, users.id AS user_id, users.handle FROM chirp_mentions
+JOIN users ON users.id = chirp_mentions.user_id
+WHERE chirp_mentions.chirp_id = ANY(@chirp_ids::uuid[])
+ORDER BY chirp_mentions.chirp_id, users.handle;
+
+-- name: ListMentionChirpsAsc :many
+SELECT chirps.* FROM chirps
+JOIN chirp_mentions ON chirp_mentions.chirp_id = chirps.id
+WHERE chirp_mentions.user_id = @user_id
+    AND chirps.user_id <> ALL(@hidden_ids::uuid[])
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT @lim;
+
+-- name: ListMentionChirpsDesc :many
+SELECT chirps.* FROM chirps
+JOIN chirp_mentions ON chirp_mentions.chirp_id = chirps.id
+WHERE chirp_mentions.user_id = @user_id
+    AND chirps.user_id <> ALL(@hidden_ids::uuid[])
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT @lim;
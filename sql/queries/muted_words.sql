@@ -0,0 +1,29 @@
+-- name: CreateMutedWord :one
+INSERT INTO muted_words (id, user_id, phrase, match_type, action, created_at, expires_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    $5
+)
+RETURNING *;
+
+-- name: ListMutedWords :many
+SELECT * FROM muted_words
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC;
+
+-- name: ListActiveMutedWords :many
+SELECT * FROM muted_words
+WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: CountActiveMutedWords :one
+SELECT COUNT(*) FROM muted_words
+WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: DeleteMutedWord :execrows
+DELETE FROM muted_words
+WHERE id = $1 AND user_id = $2;
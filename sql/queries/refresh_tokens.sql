@@ -1,19 +1,65 @@
 -- name: CreateRefToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, family_id, parent_token)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
-    $3
+    $3,
+    $4,
+    $5
 )
 RETURNING *;
 
 -- name: GetRefreshToken :one
 SELECT * FROM refresh_tokens
-WHERE token = $1; 
+WHERE token = $1 AND hashed;
+
+-- name: GetLegacyRefreshToken :one
+SELECT * FROM refresh_tokens
+WHERE token = $1 AND NOT hashed;
+
+-- name: UpgradeLegacyToken :exec
+UPDATE refresh_tokens
+SET token = sqlc.arg(hash), hashed = true, updated_at = NOW()
+WHERE token = sqlc.arg(legacy_token) AND NOT hashed;
 
 -- name: RevokeToken :exec
 UPDATE refresh_tokens
 SET updated_at = NOW(), revoked_at = NOW()
-WHERE token = $1;
\ No newline at end of file
+WHERE token = $1;
+
+-- name: RevokeActiveToken :execrows
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE token = $1 AND revoked_at IS NULL;
+
+-- name: RevokeTokenFamily :exec
+WITH revoked_session AS (
+    UPDATE sessions
+    SET revoked_at = NOW()
+    WHERE id = $1 AND revoked_at IS NULL
+)
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserTokens :exec
+WITH revoked_sessions AS (
+    UPDATE sessions
+    SET revoked_at = NOW()
+    WHERE user_id = $1 AND revoked_at IS NULL
+)
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserTokensExcept :exec
+WITH revoked_sessions AS (
+    UPDATE sessions
+    SET revoked_at = NOW()
+    WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+)
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL;
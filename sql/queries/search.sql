@@ -0,0 +1,31 @@
+-- name: SearchChirps :many
+SELECT chirps.*, ts_rank(chirps.search, query) AS rank,
+    ts_headline('english', chirps.body, query,
+        'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2, MinWords=5, MaxWords=20') AS snippet
+FROM chirps, to_tsquery('english', @query::text) AS query
+WHERE chirps.search @@ query AND chirps.deleted_at IS NULL
+    AND (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id))
+    AND (sqlc.narg(since)::timestamp IS NULL OR chirps.created_at >= sqlc.narg(since))
+    AND (sqlc.narg(until)::timestamp IS NULL OR chirps.created_at < sqlc.narg(until))
+    AND chirps.user_id <> ALL(@hidden_ids::uuid[])
+    AND (sqlc.narg(cursor_rank)::real IS NULL
+        OR (ts_rank(chirps.search, query), chirps.created_at, chirps.id)
+            < (sqlc.narg(cursor_rank), sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT @lim;
+
+-- name: SearchChirpsReverse :many
+SELECT chirps.*, ts_rank(chirps.search, query) AS rank,
+    ts_headline('english', chirps.body, query,
+        'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2, MinWords=5, MaxWords=20') AS snippet
+FROM chirps, to_tsquery('english', @query::text) AS query
+WHERE chirps.search @@ query AND chirps.deleted_at IS NULL
+    AND (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id))
+    AND (sqlc.narg(since)::timestamp IS NULL OR chirps.created_at >= sqlc.narg(since))
+    AND (sqlc.narg(until)::timestamp IS NULL OR chirps.created_at < sqlc.narg(until))
+    AND chirps.user_id <> ALL(@hidden_ids::uuid[])
+    AND (sqlc.narg(cursor_rank)::real IS NULL
+        OR (ts_rank(chirps.search, query), chirps.created_at, chirps.id)
+            > (sqlc.narg(cursor_rank), sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY rank ASC, chirps.created_at ASC, chirps.id ASC
+LIMIT @lim;
@@ -0,0 +1,22 @@
+-- name: SeedUsers :many
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
+SELECT gen_random_uuid(), NOW(), NOW(), @prefix::text || n || '@' || 'seed.invalid', '', @prefix::text || n
+FROM generate_series(1, @count::int) AS n
+RETURNING id;
+
+-- name: SeedFollows :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT follower_id, followee_id, NOW() FROM (
+    SELECT followers.id AS follower_id,
+        (@user_ids::uuid[])[1 + floor(power(random(), @skew::float8) * cardinality(@user_ids::uuid[]))::int] AS followee_id
+    FROM unnest(@user_ids::uuid[]) AS followers(id), generate_series(1, @per_user::int)
+) picks
+WHERE follower_id <> followee_id
+ON CONFLICT DO NOTHING;
+
+-- name: SeedChirps :execrows
+INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+SELECT gen_random_uuid(), posted_at, posted_at, 'seeded chirp ' || n, author_id FROM (
+    SELECT authors.id AS author_id, n, NOW() - random() * make_interval(days => @days::int) AS posted_at
+    FROM unnest(@user_ids::uuid[]) AS authors(id), generate_series(1, @per_user::int) AS n
+) seeded;
@@ -0,0 +1,34 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, created_at, user_id, last_used_at, user_agent, ip)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    NOW(),
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: GetSession :one
+SELECT * FROM sessions
+WHERE id = $1;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_used_at = NOW(), user_agent = $2, ip = $3
+WHERE id = $1;
+
+-- name: IsSessionRevoked :one
+SELECT revoked_at IS NOT NULL AS revoked FROM sessions
+WHERE id = $1;
+
+-- name: ListActiveSessions :many
+SELECT * FROM sessions
+WHERE user_id = $1 AND revoked_at IS NULL AND EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE refresh_tokens.family_id = sessions.id
+        AND refresh_tokens.revoked_at IS NULL
+        AND refresh_tokens.expires_at > NOW()
+)
+ORDER BY last_used_at DESC;
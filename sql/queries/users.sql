@@ -16,13 +16,109 @@ DELETE FROM users;
 SELECT * FROM users
 WHERE email = $1;
 
--- name: UpdateUserCredentials :one
-UPDATE users
-SET email = $2, hashed_password = $3, updated_at = NOW()
-WHERE id = $1
-RETURNING id, created_at, updated_at, email, is_chirpy_red;
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
 
 -- name: UpgradeUser :exec
 UPDATE users
 SET is_chirpy_red = true, updated_at = NOW()
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: ListUsers :many
+SELECT * FROM users
+WHERE sqlc.narg(search)::text IS NULL OR email ILIKE '%' || sqlc.narg(search) || '%'
+ORDER BY created_at, id
+LIMIT sqlc.arg(lim) OFFSET sqlc.arg(off);
+
+-- name: CountUsers :one
+SELECT COUNT(*) FROM users
+WHERE sqlc.narg(search)::text IS NULL OR email ILIKE '%' || sqlc.narg(search) || '%';
+
+-- name: GetUserStats :one
+SELECT
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = $1 AND chirps.deleted_at IS NULL) AS chirp_count,
+    (SELECT COUNT(*) FROM sessions
+        WHERE sessions.user_id = $1 AND EXISTS (
+            SELECT 1 FROM refresh_tokens
+            WHERE refresh_tokens.family_id = sessions.id
+                AND refresh_tokens.revoked_at IS NULL
+                AND refresh_tokens.expires_at > NOW()
+        )) AS session_count;
+
+-- name: SetChirpyRed :execrows
+UPDATE users
+SET is_chirpy_red = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SuspendUser :execrows
+UPDATE users
+SET suspended_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND suspended_at IS NULL;
+
+-- name: UnsuspendUser :execrows
+UPDATE users
+SET suspended_at = NULL, updated_at = NOW()
+WHERE id = $1 AND suspended_at IS NOT NULL;
+
+-- name: IsUserSuspended :one
+SELECT suspended_at IS NOT NULL AS suspended FROM users
+WHERE id = $1;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1;
+
+
+-- name: SetPendingEmail :exec
+UPDATE users
+SET pending_email = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: VerifyUserEmail :execrows
+UPDATE users
+SET email = sqlc.arg(email), pending_email = NULL, email_verified_at = NOW(), updated_at = NOW()
+WHERE id = sqlc.arg(id) AND (email = sqlc.arg(email) OR pending_email = sqlc.arg(email));
+
+-- name: SetTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2, totp_enabled_at = NULL, totp_last_step = 0, updated_at = NOW()
+WHERE id = $1;
+
+-- name: EnableTOTP :execrows
+UPDATE users
+SET totp_enabled_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL;
+
+-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UseTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1 AND totp_last_step < $2;
+
+-- name: SetUserRole :execrows
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UserHasPermission :one
+SELECT EXISTS (
+    SELECT 1 FROM users
+    JOIN role_permissions ON role_permissions.role = users.role
+    WHERE users.id = $1 AND role_permissions.permission = $2
+        AND users.suspended_at IS NULL
+);
+
+-- name: SetUserHandle :exec
+UPDATE users
+SET handle = $2, updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+    ADD family_id UUID NOT NULL DEFAULT gen_random_uuid(),
+    ADD parent_token TEXT;
+
+ALTER TABLE refresh_tokens
+    ALTER COLUMN family_id DROP DEFAULT;
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- +goose Down
+DROP INDEX refresh_tokens_family_id_idx;
+
+ALTER TABLE refresh_tokens
+    DROP COLUMN family_id,
+    DROP COLUMN parent_token
+;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+    ADD hashed BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE refresh_tokens
+    ALTER COLUMN hashed SET DEFAULT true;
+
+-- +goose Down
+DELETE FROM refresh_tokens
+WHERE hashed;
+
+ALTER TABLE refresh_tokens
+    DROP COLUMN hashed
+;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE users
+    ADD email_verified_at TIMESTAMP,
+    ADD pending_email TEXT;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN email_verified_at,
+    DROP COLUMN pending_email
+;
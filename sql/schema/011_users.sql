@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE users
+    ADD totp_secret TEXT,
+    ADD totp_enabled_at TIMESTAMP,
+    ADD totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN totp_secret,
+    DROP COLUMN totp_enabled_at,
+    DROP COLUMN totp_last_step
+;
@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE sessions(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users
+        ON DELETE CASCADE,
+    last_used_at TIMESTAMP NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+
+-- every refresh token chain issued so far becomes a session
+INSERT INTO sessions (id, created_at, user_id, last_used_at)
+SELECT family_id, MIN(created_at), user_id, MAX(updated_at)
+FROM refresh_tokens
+GROUP BY family_id, user_id;
+
+ALTER TABLE refresh_tokens
+    ADD CONSTRAINT refresh_tokens_family_id_fkey FOREIGN KEY (family_id) REFERENCES sessions(id)
+        ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+    DROP CONSTRAINT refresh_tokens_family_id_fkey;
+
+DROP TABLE sessions;
@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE login_throttles(
+    subject TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL,
+    blocked_until TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE login_throttles;
@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE roles(
+    name TEXT PRIMARY KEY
+);
+
+CREATE TABLE role_permissions(
+    role TEXT NOT NULL REFERENCES roles
+        ON DELETE CASCADE,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name)
+VALUES ('user'), ('moderator'), ('admin');
+
+INSERT INTO role_permissions (role, permission)
+VALUES
+    ('moderator', 'chirps:moderate'),
+    ('admin', 'chirps:moderate'),
+    ('admin', 'admin:metrics'),
+    ('admin', 'admin:reset'),
+    ('admin', 'admin:lockouts'),
+    ('admin', 'users:roles');
+
+ALTER TABLE users
+    ADD role TEXT NOT NULL DEFAULT 'user' REFERENCES roles(name);
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN role;
+
+DROP TABLE role_permissions;
+DROP TABLE roles;
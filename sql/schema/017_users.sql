@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE users
+    ADD suspended_at TIMESTAMP;
+
+INSERT INTO role_permissions (role, permission)
+VALUES ('admin', 'users:manage');
+
+-- +goose Down
+DELETE FROM role_permissions
+WHERE role = 'admin' AND permission = 'users:manage';
+
+ALTER TABLE users
+    DROP COLUMN suspended_at;
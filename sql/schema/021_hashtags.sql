@@ -0,0 +1,23 @@
+-- +goose Up
+-- chirps posted before this migration get their hashtags from `chirpy relink-chirps`
+CREATE TABLE hashtags(
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX hashtags_name_pattern_idx ON hashtags (name text_pattern_ops);
+
+CREATE TABLE chirp_hashtags(
+    chirp_id UUID NOT NULL REFERENCES chirps
+        ON DELETE CASCADE,
+    hashtag_id UUID NOT NULL REFERENCES hashtags
+        ON DELETE CASCADE,
+    PRIMARY KEY (chirp_id, hashtag_id)
+);
+
+CREATE INDEX chirp_hashtags_hashtag_id_idx ON chirp_hashtags (hashtag_id, chirp_id);
+
+-- +goose Down
+DROP TABLE chirp_hashtags;
+DROP TABLE hashtags;
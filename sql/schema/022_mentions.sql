@@ -0,0 +1,23 @@
+-- +goose Up
+-- chirps posted before this migration get their mentions from `chirpy relink-chirps`
+ALTER TABLE users
+    ADD handle TEXT;
+
+CREATE UNIQUE INDEX users_handle_idx ON users (LOWER(handle));
+
+CREATE TABLE chirp_mentions(
+    chirp_id UUID NOT NULL REFERENCES chirps
+        ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users
+        ON DELETE CASCADE,
+    PRIMARY KEY (chirp_id, user_id)
+);
+
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id, chirp_id);
+
+-- +goose Down
+DROP TABLE chirp_mentions;
+DROP INDEX users_handle_idx;
+
+ALTER TABLE users
+    DROP COLUMN handle;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE chirps
+    ADD in_reply_to UUID REFERENCES chirps
+        ON DELETE SET NULL,
+    ADD deleted_at TIMESTAMP;
+
+CREATE INDEX chirps_in_reply_to_idx ON chirps (in_reply_to);
+
+-- +goose Down
+DROP INDEX chirps_in_reply_to_idx;
+
+ALTER TABLE chirps
+    DROP COLUMN deleted_at,
+    DROP COLUMN in_reply_to;
@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE chirps
+    ADD rechirp_of UUID REFERENCES chirps
+        ON DELETE CASCADE,
+    ADD quote_of UUID REFERENCES chirps
+        ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX chirps_rechirp_idx ON chirps (user_id, rechirp_of)
+    WHERE rechirp_of IS NOT NULL AND deleted_at IS NULL;
+
+CREATE INDEX chirps_rechirp_of_idx ON chirps (rechirp_of);
+
+-- +goose Down
+DROP INDEX chirps_rechirp_of_idx;
+DROP INDEX chirps_rechirp_idx;
+
+ALTER TABLE chirps
+    DROP COLUMN quote_of,
+    DROP COLUMN rechirp_of;
@@ -0,0 +1,41 @@
+-- +goose Up
+CREATE TABLE likes(
+    user_id UUID NOT NULL REFERENCES users
+        ON DELETE CASCADE,
+    chirp_id UUID NOT NULL REFERENCES chirps
+        ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE INDEX likes_user_id_idx ON likes (user_id, created_at, chirp_id);
+
+-- counting likes on every read gets expensive once a chirp takes off, so
+-- the count is kept on the chirp and moved along with the likes table
+ALTER TABLE chirps
+    ADD like_count BIGINT NOT NULL DEFAULT 0;
+
+-- +goose StatementBegin
+CREATE FUNCTION count_likes() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE chirps SET like_count = like_count + 1 WHERE id = NEW.chirp_id;
+    ELSE
+        UPDATE chirps SET like_count = like_count - 1 WHERE id = OLD.chirp_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER likes_count_trigger
+    AFTER INSERT OR DELETE ON likes
+    FOR EACH ROW EXECUTE FUNCTION count_likes();
+
+-- +goose Down
+DROP TRIGGER likes_count_trigger ON likes;
+DROP FUNCTION count_likes;
+DROP TABLE likes;
+
+ALTER TABLE chirps
+    DROP COLUMN like_count;
@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE bookmark_folders(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users
+        ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX bookmark_folders_name_idx ON bookmark_folders (user_id, LOWER(name));
+
+CREATE TABLE bookmarks(
+    user_id UUID NOT NULL REFERENCES users
+        ON DELETE CASCADE,
+    chirp_id UUID NOT NULL REFERENCES chirps
+        ON DELETE CASCADE,
+    -- deleting a folder leaves its bookmarks unfiled rather than losing them
+    folder_id UUID REFERENCES bookmark_folders
+        ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE INDEX bookmarks_user_id_idx ON bookmarks (user_id, created_at, chirp_id);
+
+-- +goose Down
+DROP TABLE bookmarks;
+DROP TABLE bookmark_folders;
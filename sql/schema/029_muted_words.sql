@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE muted_words(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users
+        ON DELETE CASCADE,
+    phrase TEXT NOT NULL,
+    match_type TEXT NOT NULL CHECK (match_type IN ('word', 'regex')),
+    action TEXT NOT NULL CHECK (action IN ('hide', 'collapse')),
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP
+);
+
+CREATE INDEX muted_words_user_id_idx ON muted_words (user_id);
+
+-- +goose Down
+DROP TABLE muted_words;
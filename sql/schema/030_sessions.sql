@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE sessions
+    ADD revoked_at TIMESTAMP;
+
+-- sessions with nothing left to refresh were signed out one way or another
+UPDATE sessions
+SET revoked_at = NOW()
+WHERE NOT EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE refresh_tokens.family_id = sessions.id
+        AND refresh_tokens.revoked_at IS NULL
+);
+
+-- +goose Down
+ALTER TABLE sessions
+    DROP COLUMN revoked_at;
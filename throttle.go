@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ScooballyD/chirpy/internal/database"
+)
+
+// throttlePolicy decides how long a login subject is blocked after a run of
+// failures: a few free attempts, then a delay that doubles with each further
+// failure, then a longer lockout once the run gets long enough.
+type throttlePolicy struct {
+	free      int32
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	lockAfter int32
+	lockout   time.Duration
+}
+
+// an IP can sit in front of many accounts, so it gets more room than any
+// single account does
+var (
+	accountThrottle = throttlePolicy{
+		free:      3,
+		baseDelay: 1 * time.Second,
+		maxDelay:  5 * time.Minute,
+		lockAfter: 10,
+		lockout:   30 * time.Minute,
+	}
+	ipThrottle = throttlePolicy{
+		free:      20,
+		baseDelay: 1 * time.Second,
+		maxDelay:  5 * time.Minute,
+		lockAfter: 100,
+		lockout:   1 * time.Hour,
+	}
+)
+
+func (p throttlePolicy) delay(failures int32) time.Duration {
+	if failures >= p.lockAfter {
+		return p.lockout
+	}
+	if failures <= p.free {
+		return 0
+	}
+	d := time.Duration(float64(p.baseDelay) * math.Pow(2, float64(failures-p.free-1)))
+	if d > p.maxDelay {
+		return p.maxDelay
+	}
+	return d
+}
+
+func accountSubject(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipSubject(r *http.Request) string {
+	return "ip:" + clientIP(r)
+}
+
+type throttleSubject struct {
+	subject string
+	policy  throttlePolicy
+}
+
+// loginSubjects are the counters an attempt against email is charged to, in
+// the order their rows are locked.
+func loginSubjects(r *http.Request, email string) []throttleSubject {
+	return []throttleSubject{
+		{accountSubject(email), accountThrottle},
+		{ipSubject(r), ipThrottle},
+	}
+}
+
+var errLoginBlocked = errors.New("login is blocked")
+
+// startLoginAttempt counts an attempt against email as a failure before its
+// credentials are checked, and blocks the next one for as long as that
+// failure would. Counting and blocking happen in one transaction holding the
+// throttle rows, so concurrent guesses queue up behind each other instead of
+// all being let through before the first failure lands. An attempt made while
+// blocked isn't counted; the wait is returned instead.
+func (cfg *apiConfig) startLoginAttempt(ctx context.Context, r *http.Request, email string) (time.Duration, error) {
+	var wait time.Duration
+	err := cfg.inTx(ctx, func(q *database.Queries) error {
+		for _, s := range loginSubjects(r, email) {
+			row, err := q.RecordLoginAttempt(ctx, s.subject)
+			if err != nil {
+				return fmt.Errorf("unable to record login attempt: %v", err)
+			}
+			if d := time.Until(row.BlockedUntil); d > wait {
+				wait = d
+			}
+			d := s.policy.delay(row.Failures)
+			if d == 0 {
+				continue
+			}
+			err = q.BlockLogin(
+				ctx,
+				database.BlockLoginParams{
+					Subject:      s.subject,
+					BlockedUntil: time.Now().Add(d),
+				})
+			if err != nil {
+				return fmt.Errorf("unable to block login: %v", err)
+			}
+		}
+		if wait > 0 {
+			return errLoginBlocked
+		}
+		return nil
+	})
+	if errors.Is(err, errLoginBlocked) {
+		return wait, nil
+	}
+	return 0, err
+}
+
+// forgiveLoginAttempt takes back the failure startLoginAttempt charged for an
+// attempt that turned out to be right. Any block it set is left to run out.
+func (cfg *apiConfig) forgiveLoginAttempt(ctx context.Context, r *http.Request, email string) {
+	for _, s := range loginSubjects(r, email) {
+		err := cfg.db.ForgiveLoginAttempt(ctx, s.subject)
+		if err != nil {
+			fmt.Printf("unable to forgive login attempt: %v", err)
+		}
+	}
+}
+
+// clearLoginFailures ends an account's run of failures once it has been
+// logged into. The IP only gets its attempt back, since it may be guessing
+// at other accounts too.
+func (cfg *apiConfig) clearLoginFailures(ctx context.Context, r *http.Request, email string) {
+	err := cfg.db.ClearLoginThrottle(ctx, accountSubject(email))
+	if err != nil {
+		fmt.Printf("unable to clear login throttle: %v", err)
+	}
+	err = cfg.db.ForgiveLoginAttempt(ctx, ipSubject(r))
+	if err != nil {
+		fmt.Printf("unable to forgive login attempt: %v", err)
+	}
+}
+
+func respondWithThrottle(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+	respondWithError(w, "too many login attempts, try again later", 429)
+}
+
+func (cfg *apiConfig) listLockouts(w http.ResponseWriter, r *http.Request) {
+	rows, err := cfg.db.ListLoginLockouts(r.Context())
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve lockouts: %v", err), 500)
+		return
+	}
+
+	type lockout struct {
+		Subject       string    `json:"subject"`
+		Failures      int32     `json:"failures"`
+		LastFailureAt time.Time `json:"last_failure_at"`
+		BlockedUntil  time.Time `json:"blocked_until"`
+	}
+	resp := []lockout{}
+	for _, row := range rows {
+		resp = append(resp, lockout{
+			Subject:       row.Subject,
+			Failures:      row.Failures,
+			LastFailureAt: row.LastFailureAt,
+			BlockedUntil:  row.BlockedUntil,
+		})
+	}
+	respondWithJSON(w, resp, 200)
+}
+
+func (cfg *apiConfig) clearLockout(w http.ResponseWriter, r *http.Request) {
+	err := cfg.db.ClearLoginThrottle(r.Context(), r.PathValue("subject"))
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to clear lockout: %v", err), 500)
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type AccessToken struct {
+	Id         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+func accessTokenFromDB(pat database.PersonalAccessToken) AccessToken {
+	tkn := AccessToken{
+		Id:        pat.ID,
+		Name:      pat.Name,
+		Scopes:    pat.Scopes,
+		CreatedAt: pat.CreatedAt,
+	}
+	if pat.LastUsedAt.Valid {
+		tkn.LastUsedAt = &pat.LastUsedAt.Time
+	}
+	if pat.ExpiresAt.Valid {
+		tkn.ExpiresAt = &pat.ExpiresAt.Time
+	}
+	return tkn
+}
+
+// Personal access tokens are only managed with a login session, so a leaked
+// token can't be used to mint more of them.
+func (cfg *apiConfig) createAccessToken(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticateLogin(r)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	type req struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return
+	}
+	if Rdata.Name == "" || len(Rdata.Name) > 100 {
+		respondWithError(w, "name must be between 1 and 100 characters", 400)
+		return
+	}
+	if len(Rdata.Scopes) == 0 {
+		respondWithError(w, "at least one scope is required", 400)
+		return
+	}
+	for _, scope := range Rdata.Scopes {
+		if !auth.ValidScope(scope) {
+			respondWithError(w, fmt.Sprintf("unknown scope %q", scope), 400)
+			return
+		}
+	}
+	if Rdata.ExpiresInDays < 0 {
+		respondWithError(w, "expires_in_days can't be negative", 400)
+		return
+	}
+
+	expires := sql.NullTime{}
+	if Rdata.ExpiresInDays > 0 {
+		expires = sql.NullTime{Time: time.Now().AddDate(0, 0, Rdata.ExpiresInDays), Valid: true}
+	}
+
+	pat, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	row, err := cfg.db.CreatePersonalAccessToken(
+		r.Context(),
+		database.CreatePersonalAccessTokenParams{
+			UserID:    uid,
+			Name:      Rdata.Name,
+			Token:     auth.HashToken(pat, cfg.TokenKey),
+			Scopes:    Rdata.Scopes,
+			ExpiresAt: expires,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to save token: %v", err), 500)
+		return
+	}
+
+	resp := accessTokenFromDB(row)
+	resp.Token = pat
+	respondWithJSON(w, resp, 201)
+}
+
+func (cfg *apiConfig) listAccessTokens(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticateLogin(r)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	rows, err := cfg.db.ListPersonalAccessTokens(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to retrieve tokens: %v", err), 500)
+		return
+	}
+
+	resp := []AccessToken{}
+	for _, row := range rows {
+		resp = append(resp, accessTokenFromDB(row))
+	}
+	respondWithJSON(w, resp, 200)
+}
+
+func (cfg *apiConfig) revokeAccessToken(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticateLogin(r)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		respondWithError(w, "invalid token id", 400)
+		return
+	}
+
+	n, err := cfg.db.RevokePersonalAccessToken(
+		r.Context(),
+		database.RevokePersonalAccessTokenParams{
+			ID:     id,
+			UserID: uid,
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to revoke token: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "token not found", 404)
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+	"github.com/ScooballyD/chirpy/internal/database"
+)
+
+const recoveryCodeCount = 10
+
+func (cfg *apiConfig) enrollTOTP(w http.ResponseWriter, r *http.Request) {
+	id, err := cfg.authenticateLogin(r)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to find user: %v", err), 404)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, "two-factor authentication is already enabled", 409)
+		return
+	}
+
+	secret, err := auth.MakeTOTPSecret()
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	sealed, err := auth.SealSecret(secret, cfg.EncryptionKey)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	err = cfg.db.SetTOTPSecret(
+		r.Context(),
+		database.SetTOTPSecretParams{
+			ID:         id,
+			TotpSecret: sql.NullString{String: sealed, Valid: true},
+		})
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to save secret: %v", err), 500)
+		return
+	}
+
+	type enrollment struct {
+		Secret string `json:"secret"`
+		URI    string `json:"otpauth_uri"`
+	}
+	respondWithJSON(w, enrollment{Secret: secret, URI: auth.TOTPURI(secret, user.Email)}, 200)
+}
+
+func (cfg *apiConfig) confirmTOTP(w http.ResponseWriter, r *http.Request) {
+	id, err := cfg.authenticateLogin(r)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	type req struct {
+		Code string `json:"code"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to find user: %v", err), 404)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, "two-factor authentication is already enabled", 409)
+		return
+	}
+	if !user.TotpSecret.Valid {
+		respondWithError(w, "start enrollment before confirming", 400)
+		return
+	}
+
+	ok, err := cfg.checkTOTP(r.Context(), user, Rdata.Code)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	if !ok {
+		respondWithError(w, "invalid code", 401)
+		return
+	}
+
+	n, err := cfg.db.EnableTOTP(r.Context(), id)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to enable two-factor authentication: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "two-factor authentication is already enabled", 409)
+		return
+	}
+
+	codes, err := cfg.replaceRecoveryCodes(r.Context(), user)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+
+	type confirmation struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	respondWithJSON(w, confirmation{RecoveryCodes: codes}, 200)
+}
+
+func (cfg *apiConfig) disableTOTP(w http.ResponseWriter, r *http.Request) {
+	id, err := cfg.authenticateLogin(r)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	type req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to find user: %v", err), 404)
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		respondWithError(w, "two-factor authentication is not enabled", 409)
+		return
+	}
+
+	// a stolen access token alone mustn't be enough to strip the second factor
+	err = auth.CheckPasswordHash(Rdata.Password, user.HashedPassword)
+	if err != nil {
+		respondWithError(w, "Incorrect password or code", 401)
+		return
+	}
+	ok, err := cfg.checkSecondFactor(r.Context(), user, Rdata.Code)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	if !ok {
+		respondWithError(w, "Incorrect password or code", 401)
+		return
+	}
+
+	err = cfg.db.DisableTOTP(r.Context(), id)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to disable two-factor authentication: %v", err), 500)
+		return
+	}
+	err = cfg.db.DeleteRecoveryCodes(r.Context(), id)
+	if err != nil {
+		fmt.Printf("unable to delete recovery codes: %v", err)
+	}
+
+	w.WriteHeader(204)
+}
+
+// loginMFA is the second step of a login for accounts with two-factor
+// authentication, trading the mfa token from loginUser and a code for a
+// session.
+func (cfg *apiConfig) loginMFA(w http.ResponseWriter, r *http.Request) {
+	type req struct {
+		Token string `json:"mfa_token"`
+		Code  string `json:"code"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return
+	}
+
+	id, err := auth.ValidateMFAToken(Rdata.Token, cfg.Keys)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unauthorized: %v", err), 401)
+		return
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, "unauthorized", 401)
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		respondWithError(w, "two-factor authentication is not enabled", 400)
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondWithError(w, "account is suspended", 403)
+		return
+	}
+
+	// codes are short enough to guess, so they count against the same
+	// throttle as passwords
+	wait, err := cfg.startLoginAttempt(r.Context(), r, user.Email)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	if wait > 0 {
+		respondWithThrottle(w, wait)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(r.Context(), user, Rdata.Code)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	if !ok {
+		respondWithError(w, "invalid code", 401)
+		return
+	}
+	cfg.clearLoginFailures(r.Context(), r, user.Email)
+
+	cfg.issueSession(w, r, user)
+}
+
+// checkSecondFactor accepts either a current TOTP code or one of the user's
+// unused recovery codes, consuming whichever matched.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, user database.User, code string) (bool, error) {
+	if len(code) == 6 {
+		return cfg.checkTOTP(ctx, user, code)
+	}
+
+	n, err := cfg.db.UseRecoveryCode(
+		ctx,
+		database.UseRecoveryCodeParams{
+			Code:   auth.HashToken(auth.NormalizeRecoveryCode(code), cfg.TokenKey),
+			UserID: user.ID,
+		})
+	if err != nil {
+		return false, fmt.Errorf("unable to check recovery code: %v", err)
+	}
+	return n == 1, nil
+}
+
+func (cfg *apiConfig) checkTOTP(ctx context.Context, user database.User, code string) (bool, error) {
+	secret, err := auth.OpenSecret(user.TotpSecret.String, cfg.EncryptionKey)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := auth.ValidateTOTP(secret, code, time.Now(), user.TotpLastStep)
+	if !ok {
+		return false, nil
+	}
+	// recording the step atomically is what stops the same code being
+	// accepted twice by concurrent requests
+	n, err := cfg.db.UseTOTPStep(
+		ctx,
+		database.UseTOTPStepParams{
+			ID:           user.ID,
+			TotpLastStep: step,
+		})
+	if err != nil {
+		return false, fmt.Errorf("unable to record code use: %v", err)
+	}
+	return n == 1, nil
+}
+
+func (cfg *apiConfig) replaceRecoveryCodes(ctx context.Context, user database.User) ([]string, error) {
+	err := cfg.db.DeleteRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to delete recovery codes: %v", err)
+	}
+
+	codes, err := auth.MakeRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		err = cfg.db.CreateRecoveryCode(
+			ctx,
+			database.CreateRecoveryCodeParams{
+				Code:   auth.HashToken(code, cfg.TokenKey),
+				UserID: user.ID,
+			})
+		if err != nil {
+			return nil, fmt.Errorf("unable to save recovery code: %v", err)
+		}
+	}
+	return codes, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/ScooballyD/chirpy/internal/mail"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const emailVerificationTTL = 48 * time.Hour
+
+// sendVerification mails a confirmation link for email, which is either the
+// user's current address or the one they asked to change to.
+func (cfg *apiConfig) sendVerification(ctx context.Context, base string, userID uuid.UUID, email string) error {
+	err := cfg.db.InvalidateEmailVerificationTokens(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("unable to invalidate verification tokens: %v", err)
+	}
+
+	tkn, err := auth.MakeToken()
+	if err != nil {
+		return fmt.Errorf("unable to make verification token: %v", err)
+	}
+	err = cfg.db.CreateEmailVerificationToken(
+		ctx,
+		database.CreateEmailVerificationTokenParams{
+			Token:     auth.HashToken(tkn, cfg.TokenKey),
+			UserID:    userID,
+			Email:     email,
+			ExpiresAt: time.Now().Add(emailVerificationTTL),
+		})
+	if err != nil {
+		return fmt.Errorf("unable to save verification token: %v", err)
+	}
+
+	link := base + "/app/verify-email?token=" + url.QueryEscape(tkn)
+	err = cfg.Mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Confirm your email for Chirpy",
+		Body: fmt.Sprintf(
+			"Confirm this address for your Chirpy account by opening:\n%v\n\n"+
+				"The link expires in %v. If you didn't ask for this, you can ignore this email.\n",
+			link, emailVerificationTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to send verification email: %v", err)
+	}
+	return nil
+}
+
+func (cfg *apiConfig) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	type req struct {
+		Token string `json:"token"`
+	}
+	Rdata := req{}
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&Rdata)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to decode request: %v", err), 400)
+		return
+	}
+
+	hash := auth.HashToken(Rdata.Token, cfg.TokenKey)
+	tkn, err := cfg.db.GetEmailVerificationToken(r.Context(), hash)
+	if err != nil {
+		respondWithError(w, "invalid or expired verification token", 400)
+		return
+	}
+	n, err := cfg.db.UseEmailVerificationToken(r.Context(), hash)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to use verification token: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, "invalid or expired verification token", 400)
+		return
+	}
+
+	n, err = cfg.db.VerifyUserEmail(
+		r.Context(),
+		database.VerifyUserEmailParams{
+			Email: tkn.Email,
+			ID:    tkn.UserID,
+		})
+	if err != nil {
+		if isUniqueViolation(err) {
+			respondWithError(w, "email is already in use", 409)
+			return
+		}
+		respondWithError(w, fmt.Sprintf("unable to verify email: %v", err), 500)
+		return
+	}
+	if n == 0 {
+		// the address was changed again after this link was sent
+		respondWithError(w, "invalid or expired verification token", 400)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) resendVerification(w http.ResponseWriter, r *http.Request) {
+	id, err := cfg.authenticateLogin(r)
+	if err != nil {
+		fmt.Println(err)
+		respondWithAuthError(w, err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), id)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("unable to find user: %v", err), 404)
+		return
+	}
+
+	email := user.Email
+	if user.PendingEmail.Valid {
+		email = user.PendingEmail.String
+	} else if user.EmailVerifiedAt.Valid {
+		respondWithError(w, "email is already verified", 409)
+		return
+	}
+
+	err = cfg.sendVerification(r.Context(), cfg.BaseURL, user.ID, email)
+	if err != nil {
+		respondWithError(w, fmt.Sprintf("%v", err), 500)
+		return
+	}
+	w.WriteHeader(202)
+}
+
+func validEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	return at > 0 && at < len(email)-1 && !strings.ContainsAny(email, " \r\n")
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/ScooballyD/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// visibility is what one reader may see. A block hides two accounts from
+// each other entirely; a mute only keeps the muted account out of the
+// muter's listings, and a muted word collapses or hides matching chirps for
+// the muter alone. Every chirp listing filters on hidden and every response
+// is hydrated through the same visibility, so the rules live here rather
+// than in each handler.
+type visibility struct {
+	viewer  uuid.NullUUID
+	hidden  []uuid.UUID
+	blocked map[uuid.UUID]bool
+	muted   map[uuid.UUID]bool
+	words   []wordFilter
+}
+
+func (cfg *apiConfig) visibilityFor(ctx context.Context, viewer uuid.NullUUID) (visibility, error) {
+	// hidden is passed to queries as <> ALL(...), which filters out
+	// everything if it goes over as NULL rather than an empty array
+	v := visibility{
+		viewer:  viewer,
+		hidden:  []uuid.UUID{},
+		blocked: map[uuid.UUID]bool{},
+		muted:   map[uuid.UUID]bool{},
+	}
+	if !viewer.Valid {
+		return v, nil
+	}
+
+	rows, err := cfg.db.ListHiddenAuthors(ctx, viewer.UUID)
+	if err != nil {
+		return v, fmt.Errorf("unable to retrieve blocks and mutes: %v", err)
+	}
+	for _, row := range rows {
+		if !v.blocked[row.AuthorID] && !v.muted[row.AuthorID] {
+			v.hidden = append(v.hidden, row.AuthorID)
+		}
+		if row.Blocked {
+			v.blocked[row.AuthorID] = true
+		} else {
+			v.muted[row.AuthorID] = true
+		}
+	}
+
+	words, err := cfg.db.ListActiveMutedWords(ctx, viewer.UUID)
+	if err != nil {
+		return v, fmt.Errorf("unable to retrieve muted words: %v", err)
+	}
+	for _, word := range words {
+		// every stored word compiled when it was added
+		re, err := compileMutedWord(word.Phrase, word.MatchType)
+		if err != nil {
+			continue
+		}
+		v.words = append(v.words, wordFilter{
+			phrase: word.Phrase,
+			re:     re,
+			hide:   word.Action == actionHide,
+		})
+	}
+	return v, nil
+}
+
+// readerVisibility is visibilityFor whoever is reading a public endpoint,
+// signed in or not.
+func (cfg *apiConfig) readerVisibility(r *http.Request) (visibility, error) {
+	return cfg.visibilityFor(r.Context(), cfg.viewer(r))
+}
+
+func (cfg *apiConfig) userVisibility(ctx context.Context, uid uuid.UUID) (visibility, error) {
+	return cfg.visibilityFor(ctx, uuid.NullUUID{UUID: uid, Valid: true})
+}
+
+// hides reports whether an author's chirps are left out of listings.
+func (v visibility) hides(author uuid.UUID) bool {
+	return v.blocked[author] || v.muted[author]
+}
+
+// blocks reports whether an author is on the other side of a block, so not
+// even a direct link shows their chirps.
+func (v visibility) blocks(author uuid.UUID) bool {
+	return v.blocked[author]
+}
+
+// conceal empties a chirp the reader shouldn't see while keeping its place,
+// the way a tombstone does for a deleted one.
+func (c *Chirp) conceal() {
+	*c = Chirp{
+		Id:        c.Id,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		InReplyTo: c.InReplyTo,
+		Mentions:  []Mention{},
+		Hidden:    true,
+	}
+}
+
+// chirpFor is originalChirp as uid sees it: chirps by someone on either side
+// of a block with them don't exist, so they can't be replied to, quoted,
+// rechirped, liked or bookmarked.
+func chirpFor(ctx context.Context, q *database.Queries, uid, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := originalChirp(ctx, q, id)
+	if err != nil {
+		return chirp, err
+	}
+	blocked, err := q.IsBlockedBetween(
+		ctx,
+		database.IsBlockedBetweenParams{
+			A: uid,
+			B: chirp.UserID,
+		})
+	if err != nil {
+		return chirp, fmt.Errorf("unable to check blocks: %v", err)
+	}
+	if blocked {
+		return chirp, sql.ErrNoRows
+	}
+	return chirp, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ScooballyD/chirpy/internal/auth"
+)
+
+func (cfg *apiConfig) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, cfg.Keys.JWKS(), 200)
+}
+
+func (cfg *apiConfig) discoveryHandler(w http.ResponseWriter, r *http.Request) {
+	type discovery struct {
+		Issuer             string   `json:"issuer"`
+		JWKSURI            string   `json:"jwks_uri"`
+		TokenEndpoint      string   `json:"token_endpoint"`
+		RefreshEndpoint    string   `json:"refresh_endpoint"`
+		RevocationEndpoint string   `json:"revocation_endpoint"`
+		SigningAlgs        []string `json:"id_token_signing_alg_values_supported"`
+		SubjectTypes       []string `json:"subject_types_supported"`
+	}
+
+	// built from BASE_URL, never the Host header, since shared caches keep
+	// this document for everyone
+	base := cfg.BaseURL
+	doc := discovery{
+		Issuer:             auth.Issuer,
+		JWKSURI:            base + "/.well-known/jwks.json",
+		TokenEndpoint:      base + "/api/login",
+		RefreshEndpoint:    base + "/api/refresh",
+		RevocationEndpoint: base + "/api/revoke",
+		SigningAlgs:        cfg.Keys.PublicMethods(),
+		SubjectTypes:       []string{"public"},
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, doc, 200)
+}